@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,10 +16,13 @@ import (
 
 	"timesheet-filler/internal/config"
 	"timesheet-filler/internal/handlers"
+	"timesheet-filler/internal/holidays"
 	"timesheet-filler/internal/i18n"
 	"timesheet-filler/internal/metrics"
 	"timesheet-filler/internal/middleware"
+	"timesheet-filler/internal/models"
 	"timesheet-filler/internal/services"
+	"timesheet-filler/internal/utils"
 )
 
 var favicon []byte
@@ -36,13 +41,31 @@ func main() {
 	metricsMiddleware := middleware.NewMetricsMiddleware()
 	loggingMiddleware := middleware.NewLoggingMiddleware()
 	languageMiddleware := middleware.NewLanguageMiddleware("en", []string{"en", "cs"})
+	clientMiddleware := middleware.NewClientMiddleware("timesheet_client", 86400*365)
 
 	metrics.SetMetrics(metricsMiddleware)
 
-	// Initialize services
-	fileStore := services.NewFileStore(cfg.FileTokenExpiry, 10*time.Minute)
-	excelService := services.NewExcelService(cfg.TemplatePath, cfg.SheetName)
-	templateService := services.NewTemplateService(cfg.TemplateDir, translator)
+	// Initialize services shared by the tenants
+	splitOptions := services.EventSplitOptions{
+		DailyCap: cfg.MultiDayDailyCap,
+		DayStart: cfg.MultiDayDayStart,
+	}
+	holidayCalendar, err := holidays.New(cfg.HolidayCountry, cfg.HolidayExtraDays)
+	if err != nil {
+		log.Fatalf("failed to initialize holiday calendar: %v", err)
+	}
+	attendanceRules := services.DefaultAttendanceRules()
+	if len(cfg.AttendanceAccepted) > 0 {
+		attendanceRules.Accepted = cfg.AttendanceAccepted
+	}
+	if len(cfg.AttendanceDeclined) > 0 {
+		attendanceRules.Declined = cfg.AttendanceDeclined
+	}
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Printf("Unknown timezone %q, using local time: %v", cfg.Timezone, err)
+		location = time.Local
+	}
 
 	var emailService *services.EmailService
 	if cfg.EmailEnabled {
@@ -93,14 +116,44 @@ func main() {
 		)
 	}
 
-	// Initialize handlers
-	uploadHandler := handlers.NewUploadHandler(excelService, fileStore, templateService, cfg.MaxUploadSize)
-	selectSheetHandler := handlers.NewSelectSheetHandler(excelService, fileStore, templateService)
-	editHandler := handlers.NewEditHandler(excelService, fileStore, templateService)
-	processHandler := handlers.NewProcessHandler(excelService, fileStore, templateService, cfg.EmailEnabled)
-	downloadHandler := handlers.NewDownloadHandler(fileStore)
+	// Without a configured secret, personal links only last until a restart
+	linkSecret := cfg.LinkSecret
+	if linkSecret == "" {
+		log.Println("LINK_SECRET is not set, personal links stop working on restart")
+		linkSecret = utils.GenerateToken()
+	}
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+
+	shared := sharedServices{
+		translator:        translator,
+		emailService:      emailService,
+		holidayCalendar:   holidayCalendar,
+		location:          location,
+		splitOptions:      splitOptions,
+		attendanceRules:   attendanceRules,
+		linkSecret:        linkSecret,
+		schedulerCtx:      schedulerCtx,
+		loggingMiddleware: loggingMiddleware,
+		metricsMiddleware: metricsMiddleware,
+	}
+
+	// Requests matching no tenant are served by the default one, configured
+	// through the environment
+	tenantRouter := middleware.NewTenantMiddleware(newTenantHandler(cfg, models.Tenant{}, shared))
+	if cfg.TenantsFile != "" {
+		tenants, err := config.LoadTenants(cfg.TenantsFile)
+		if err != nil {
+			log.Fatalf("failed to load tenants: %v", err)
+		}
+		for _, tenant := range tenants {
+			tenantRouter.Add(tenant, newTenantHandler(cfg, tenant, shared))
+			log.Printf("Serving tenant %q (hosts: %v, path prefix: %q)", tenant.ID, tenant.Hosts, tenant.PathPrefix)
+		}
+	}
+
 	healthHandler := handlers.NewHealthHandler()
-	emailhandler := handlers.NewEmailHandler(fileStore, emailService, templateService, cfg.EmailEnabled)
 
 	// Set up HTTP router
 	baseMux := http.NewServeMux()
@@ -114,44 +167,11 @@ func main() {
 	baseMux.Handle("/favicon.svg", http.FileServer(http.Dir(cfg.TemplateDir+"/favicon")))
 	baseMux.Handle("/favicon.ico", http.FileServer(http.Dir(cfg.TemplateDir+"/favicon")))
 
-	// Application routes with middleware
-	baseMux.Handle("/", applyMiddlewares(
-		http.HandlerFunc(uploadHandler.UploadFormHandler),
-		loggingMiddleware.LogRequest,
-		metricsMiddleware.Instrument("uploadFormHandler")))
-
-	baseMux.Handle("/upload", applyMiddlewares(
-		http.HandlerFunc(uploadHandler.UploadFileHandler),
-		loggingMiddleware.LogRequest,
-		metricsMiddleware.Instrument("uploadFileHandler")))
+	// Application routes of the tenants
+	baseMux.Handle("/", tenantRouter)
 
-	baseMux.Handle("/edit", applyMiddlewares(
-		http.HandlerFunc(editHandler.EditHandler),
-		loggingMiddleware.LogRequest,
-		metricsMiddleware.Instrument("editHandler")))
-
-	baseMux.Handle("/process", applyMiddlewares(
-		http.HandlerFunc(processHandler.ProcessHandler),
-		loggingMiddleware.LogRequest,
-		metricsMiddleware.Instrument("processHandler")))
-
-	baseMux.Handle("/download/", applyMiddlewares(
-		http.HandlerFunc(downloadHandler.DownloadHandler),
-		loggingMiddleware.LogRequest,
-		metricsMiddleware.Instrument("downloadHandler")))
-
-	baseMux.Handle("/select-sheet", applyMiddlewares(
-		http.HandlerFunc(selectSheetHandler.SelectSheetHandler),
-		loggingMiddleware.LogRequest,
-		metricsMiddleware.Instrument("selectSheetHandler")))
-
-	baseMux.Handle("/send-email", applyMiddlewares(
-		http.HandlerFunc(emailhandler.SendEmailHandler),
-		loggingMiddleware.LogRequest,
-		metricsMiddleware.Instrument("sendEmailHandler")))
-
-	// Apply language middleware to all routes
-	rootHandler := languageMiddleware.DetectLanguage(baseMux)
+	// Apply language and client middleware to all routes
+	rootHandler := languageMiddleware.DetectLanguage(clientMiddleware.IdentifyClient(baseMux))
 
 	// Create servers
 	srv := &http.Server{
@@ -192,6 +212,7 @@ func main() {
 
 	log.Print("Shutting down...")
 	healthHandler.SetNotReady()
+	stopScheduler()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -214,3 +235,326 @@ func applyMiddlewares(h http.Handler, middlewares ...func(http.Handler) http.Han
 	}
 	return h
 }
+
+// sharedServices are used by the handlers of all tenants
+type sharedServices struct {
+	translator        *i18n.Translator
+	emailService      *services.EmailService
+	holidayCalendar   *holidays.Calendar
+	location          *time.Location
+	splitOptions      services.EventSplitOptions
+	attendanceRules   services.AttendanceRules
+	linkSecret        string
+	schedulerCtx      context.Context
+	loggingMiddleware *middleware.LoggingMiddleware
+	metricsMiddleware *middleware.MetricsMiddleware
+}
+
+// newTenantHandler sets up the services and routes of a tenant. Each tenant
+// keeps its own sessions, drafts, versions, submissions and report templates,
+// so file tokens of one tenant are unknown to the others.
+func newTenantHandler(cfg *config.Config, tenant models.Tenant, shared sharedServices) http.Handler {
+	loggingMiddleware := shared.loggingMiddleware
+	metricsMiddleware := shared.metricsMiddleware
+
+	dataDir := cfg.DataDir
+	if dataDir != "" && tenant.ID != "" {
+		dataDir = filepath.Join(cfg.DataDir, "tenants", tenant.ID)
+	}
+	sheetName := cfg.SheetName
+	if tenant.SheetName != "" {
+		sheetName = tenant.SheetName
+	}
+	templatePath := cfg.TemplatePath
+	if tenant.TemplatePath != "" {
+		templatePath = tenant.TemplatePath
+	}
+	coordinatorUser, coordinatorPass := cfg.CoordinatorUser, cfg.CoordinatorPass
+	if tenant.CoordinatorUser != "" {
+		coordinatorUser = tenant.CoordinatorUser
+	}
+	if tenant.CoordinatorPass != "" {
+		coordinatorPass = tenant.CoordinatorPass
+	}
+	adminUser, adminPass := cfg.AdminUser, cfg.AdminPass
+	if tenant.AdminUser != "" {
+		adminUser = tenant.AdminUser
+	}
+	if tenant.AdminPass != "" {
+		adminPass = tenant.AdminPass
+	}
+	memberEmails := cfg.MemberEmails
+	if len(tenant.MemberEmails) > 0 {
+		memberEmails = tenant.MemberEmails
+	}
+	publicURL := strings.TrimSuffix(cfg.PublicURL, "/") + tenant.PathPrefix
+	if tenant.PublicURL != "" {
+		publicURL = tenant.PublicURL
+	}
+
+	coordinatorAuth := middleware.NewBasicAuthMiddleware("Coordinators", coordinatorUser, coordinatorPass)
+	adminAuth := middleware.NewBasicAuthMiddleware("Administrators", adminUser, adminPass)
+
+	translator := shared.translator
+	if len(tenant.Translations) > 0 {
+		translator = translator.WithOverrides(tenant.Translations)
+	}
+	emailService := shared.emailService.WithRouting(tenant.EmailFromEmail, tenant.EmailFromName, tenant.EmailRecipients)
+	holidayCalendar := shared.holidayCalendar
+	location := shared.location
+	splitOptions := shared.splitOptions
+
+	// Initialize services
+	fileStore := services.NewFileStore(cfg.FileTokenExpiry, 10*time.Minute)
+	draftStore := services.NewDraftStore(cfg.DraftExpiry, time.Hour, dataDir)
+	versionStore := services.NewVersionStore(dataDir)
+	submissionStore := services.NewSubmissionStore(dataDir)
+	reportTemplateStore := services.NewReportTemplateStore(dataDir)
+	profileStore := services.NewProfileStore(dataDir)
+	excelOptions := []services.ExcelOption{
+		services.WithEventSplitOptions(splitOptions),
+		services.WithHolidayCalendar(holidayCalendar),
+		services.WithEventTypeFilter(services.EventTypeFilter{
+			Include: cfg.EventTypesInclude,
+			Exclude: cfg.EventTypesExclude,
+		}),
+		services.WithAttendanceRules(shared.attendanceRules),
+		services.WithDateLayouts(cfg.DateLayouts),
+		services.WithReportTemplates(reportTemplateStore),
+		services.WithMemberProfiles(profileStore),
+	}
+	if tenant.Mapping != nil {
+		excelOptions = append(excelOptions, services.WithReportMapping(*tenant.Mapping))
+	}
+	excelService := services.NewExcelService(templatePath, sheetName, excelOptions...)
+	icalService := services.NewICalService(location, splitOptions)
+	dashboardService := services.NewDashboardService(excelService, versionStore, submissionStore, profileStore, dataDir)
+	contacts := services.NewMemberContacts(profileStore, services.ParseMemberEmails(memberEmails), submissionStore)
+	// Links of one tenant are not valid for another
+	linkService := services.NewPersonalLinkService([]byte(shared.linkSecret+"/"+tenant.ID), cfg.LinkExpiry, publicURL)
+	templateService := services.NewTemplateService(
+		cfg.TemplateDir,
+		translator,
+		services.WithBasePath(tenant.PathPrefix),
+		services.WithBranding(tenant.Branding),
+	)
+	pdfService := services.NewPDFService(excelService.ReportMappingFor)
+	validationService := services.NewValidationService(
+		cfg.MaxDailyHours,
+		excelService.ReportMappingFor,
+		cfg.SplitOvernightRows,
+		splitOptions,
+		holidayCalendar,
+	)
+
+	// Remind members of missing timesheets after the month ends
+	if cfg.ReminderSchedule != "" {
+		schedule, err := services.ParseCronSchedule(cfg.ReminderSchedule)
+		if err != nil {
+			log.Fatalf("invalid reminder schedule: %v", err)
+		}
+		if dataDir == "" {
+			log.Println("DATA_DIR is not set, replicas cannot coordinate reminders")
+		}
+		reminderService := services.NewReminderService(dashboardService, submissionStore, emailService, translator, services.ReminderOptions{
+			Schedule:     schedule,
+			Location:     location,
+			MemberEmails: services.ParseMemberEmails(memberEmails),
+			PublicURL:    publicURL,
+			Language:     cfg.ReminderLanguage,
+			DataDir:      dataDir,
+			Profiles:     profileStore,
+			Links:        linkService,
+		})
+		go reminderService.Start(shared.schedulerCtx)
+	}
+
+	// Initialize handlers
+	uploadHandler := handlers.NewUploadHandler(excelService, icalService, fileStore, draftStore, templateService, cfg.MaxUploadSize)
+	selectSheetHandler := handlers.NewSelectSheetHandler(excelService, fileStore, templateService)
+	editHandler := handlers.NewEditHandler(excelService, icalService, fileStore, draftStore, templateService)
+	processHandler := handlers.NewProcessHandler(excelService, pdfService, fileStore, templateService, validationService, versionStore, profileStore, cfg.EmailEnabled)
+	downloadHandler := handlers.NewDownloadHandler(fileStore)
+	calendarHandler := handlers.NewCalendarHandler(excelService, icalService, fileStore)
+	draftHandler := handlers.NewDraftHandler(draftStore)
+	versionHandler := handlers.NewVersionHandler(versionStore, submissionStore, fileStore, templateService)
+	dashboardHandler := handlers.NewDashboardHandler(excelService, dashboardService, fileStore, templateService, cfg.MaxUploadSize)
+	submissionHandler := handlers.NewSubmissionHandler(excelService, versionStore, submissionStore, profileStore, emailService, templateService, cfg.EmailEnabled)
+	profileHandler := handlers.NewProfileHandler(profileStore, templateService)
+	linkHandler := handlers.NewPersonalLinkHandler(excelService, dashboardService, fileStore, linkService, contacts, emailService, templateService, cfg.EmailEnabled)
+	diagnosticsHandler := handlers.NewDiagnosticsHandler(excelService, fileStore, templateService)
+	reportTemplateHandler := handlers.NewReportTemplateHandler(excelService, reportTemplateStore, templateService, cfg.MaxUploadSize)
+	emailhandler := handlers.NewEmailHandler(fileStore, emailService, versionStore, profileStore, templateService, cfg.EmailEnabled)
+
+	mux := http.NewServeMux()
+
+	// Application routes with middleware
+	mux.Handle("/", applyMiddlewares(
+		http.HandlerFunc(uploadHandler.UploadFormHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("uploadFormHandler")))
+
+	mux.Handle("/upload", applyMiddlewares(
+		http.HandlerFunc(uploadHandler.UploadFileHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("uploadFileHandler")))
+
+	mux.Handle("/upload/diagnostics", applyMiddlewares(
+		http.HandlerFunc(diagnosticsHandler.DiagnosticsHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("diagnosticsHandler")))
+
+	mux.Handle("/upload/diagnostics.xlsx", applyMiddlewares(
+		http.HandlerFunc(diagnosticsHandler.AnnotatedHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("diagnosticsAnnotatedHandler")))
+
+	mux.Handle("/edit", applyMiddlewares(
+		http.HandlerFunc(editHandler.EditHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("editHandler")))
+
+	mux.Handle("/process", applyMiddlewares(
+		http.HandlerFunc(processHandler.ProcessHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("processHandler")))
+
+	mux.Handle("/download/", applyMiddlewares(
+		http.HandlerFunc(downloadHandler.DownloadHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("downloadHandler")))
+
+	mux.Handle("/calendar.ics", applyMiddlewares(
+		http.HandlerFunc(calendarHandler.ExportHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("calendarExportHandler")))
+
+	mux.Handle("/draft/save", applyMiddlewares(
+		http.HandlerFunc(draftHandler.SaveHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("draftSaveHandler")))
+
+	mux.Handle("/versions", applyMiddlewares(
+		http.HandlerFunc(versionHandler.VersionsHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("versionsHandler")))
+
+	mux.Handle("/me", applyMiddlewares(
+		http.HandlerFunc(linkHandler.OpenHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("personalLinkHandler")))
+
+	mux.Handle("/submit", applyMiddlewares(
+		http.HandlerFunc(submissionHandler.SubmitHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("submitHandler")))
+
+	mux.Handle("/submission", applyMiddlewares(
+		http.HandlerFunc(submissionHandler.StatusHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("submissionStatusHandler")))
+
+	// Coordinator routes
+	mux.Handle("/coordinator/dashboard", applyMiddlewares(
+		http.HandlerFunc(dashboardHandler.DashboardHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("dashboardHandler")))
+
+	mux.Handle("/coordinator/dashboard.csv", applyMiddlewares(
+		http.HandlerFunc(dashboardHandler.CSVHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("dashboardCSVHandler")))
+
+	mux.Handle("/coordinator/versions", applyMiddlewares(
+		http.HandlerFunc(versionHandler.CoordinatorVersionsHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("coordinatorVersionsHandler")))
+
+	mux.Handle("/coordinator/submissions", applyMiddlewares(
+		http.HandlerFunc(submissionHandler.QueueHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("submissionQueueHandler")))
+
+	mux.Handle("/coordinator/submissions/review", applyMiddlewares(
+		http.HandlerFunc(submissionHandler.ReviewHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("submissionReviewHandler")))
+
+	mux.Handle("/coordinator/submissions/download", applyMiddlewares(
+		http.HandlerFunc(submissionHandler.DownloadHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("submissionDownloadHandler")))
+
+	mux.Handle("/coordinator/profiles", applyMiddlewares(
+		http.HandlerFunc(profileHandler.ListHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("profilesHandler")))
+
+	mux.Handle("/coordinator/profiles/save", applyMiddlewares(
+		http.HandlerFunc(profileHandler.SaveHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("profileSaveHandler")))
+
+	mux.Handle("/coordinator/profiles/delete", applyMiddlewares(
+		http.HandlerFunc(profileHandler.DeleteHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("profileDeleteHandler")))
+
+	mux.Handle("/coordinator/links", applyMiddlewares(
+		http.HandlerFunc(linkHandler.ListHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("personalLinksHandler")))
+
+	mux.Handle("/coordinator/links/send", applyMiddlewares(
+		http.HandlerFunc(linkHandler.SendHandler),
+		coordinatorAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("personalLinksSendHandler")))
+
+	// Admin routes
+	mux.Handle("/admin/templates", applyMiddlewares(
+		http.HandlerFunc(reportTemplateHandler.ListHandler),
+		adminAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("reportTemplatesHandler")))
+
+	mux.Handle("/admin/templates/upload", applyMiddlewares(
+		http.HandlerFunc(reportTemplateHandler.UploadHandler),
+		adminAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("reportTemplateUploadHandler")))
+
+	mux.Handle("/admin/templates/preview", applyMiddlewares(
+		http.HandlerFunc(reportTemplateHandler.PreviewHandler),
+		adminAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("reportTemplatePreviewHandler")))
+
+	mux.Handle("/admin/templates/activate", applyMiddlewares(
+		http.HandlerFunc(reportTemplateHandler.ActivateHandler),
+		adminAuth.Require,
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("reportTemplateActivateHandler")))
+
+	mux.Handle("/select-sheet", applyMiddlewares(
+		http.HandlerFunc(selectSheetHandler.SelectSheetHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("selectSheetHandler")))
+
+	mux.Handle("/send-email", applyMiddlewares(
+		http.HandlerFunc(emailhandler.SendEmailHandler),
+		loggingMiddleware.LogRequest,
+		metricsMiddleware.Instrument("sendEmailHandler")))
+
+	return mux
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"timesheet-filler/internal/config"
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/i18n"
+	"timesheet-filler/internal/metrics"
+	"timesheet-filler/internal/middleware"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+	"timesheet-filler/internal/testutil"
+)
+
+var fileTokenPattern = regexp.MustCompile(`name="fileToken" value="([0-9a-f]+)"`)
+
+// TestTenantIsolation uploads an export to one tenant and checks that its
+// session is unknown to the other one
+func TestTenantIsolation(t *testing.T) {
+	translator, err := i18n.NewTranslator("../../translations", "en")
+	if err != nil {
+		t.Fatalf("failed to initialize translator: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.TemplateDir = "../../templates"
+	cfg.TemplatePath = "../../gorily_timesheet_template_2024.xlsx"
+	cfg.SheetName = "docházka realizačního týmu"
+	cfg.DataDir = ""
+	cfg.ReminderSchedule = ""
+
+	metricsMiddleware := middleware.NewMetricsMiddleware()
+	metrics.SetMetrics(metricsMiddleware)
+
+	shared := sharedServices{
+		translator:        translator,
+		emailService:      services.NewEmailService(services.ProviderSendGrid, "", "", nil, "", "", "", "", "", "", "", "", "", "", ""),
+		attendanceRules:   services.DefaultAttendanceRules(),
+		linkSecret:        "secret",
+		schedulerCtx:      context.Background(),
+		loggingMiddleware: middleware.NewLoggingMiddleware(),
+		metricsMiddleware: metricsMiddleware,
+	}
+	router := middleware.NewTenantMiddleware(newTenantHandler(cfg, models.Tenant{}, shared))
+	router.Add(models.Tenant{ID: "partner", PathPrefix: "/partner"}, newTenantHandler(cfg, models.Tenant{ID: "partner", PathPrefix: "/partner"}, shared))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.LanguageKey, "en"))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("excelFile", "export.xlsx")
+	part.Write(testutil.CreateTestExcelFile(t))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := serve(req)
+	match := fileTokenPattern.FindStringSubmatch(rec.Body.String())
+	if rec.Code != http.StatusOK || match == nil {
+		t.Fatalf("Expected the upload to offer a session, got status %d", rec.Code)
+	}
+
+	query := url.Values{}
+	query.Set("fileToken", match[1])
+	query.Set("name", "Test User")
+	query.Set("month", "1")
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/edit?"+query.Encode(), nil)); rec.Code != http.StatusOK {
+		t.Errorf("Expected the session to open in its own tenant, got status %d", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/partner/edit?"+query.Encode(), nil)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected the session to be unknown to another tenant, got status %d", rec.Code)
+	}
+}
@@ -15,6 +15,31 @@ type Config struct {
 	MaxUploadSize      int64
 	FileTokenExpiry    time.Duration
 	SheetName          string
+	EventTypesInclude  []string
+	EventTypesExclude  []string
+	AttendanceAccepted []string
+	AttendanceDeclined []string
+	DateLayouts        []string
+	MaxDailyHours      float64
+	SplitOvernightRows bool
+	MultiDayDailyCap   time.Duration
+	MultiDayDayStart   time.Duration
+	Timezone           string
+	HolidayCountry     string
+	HolidayExtraDays   []string
+	DataDir            string
+	DraftExpiry        time.Duration
+	CoordinatorUser    string
+	CoordinatorPass    string
+	AdminUser          string
+	AdminPass          string
+	TenantsFile        string
+	ReminderSchedule   string
+	ReminderLanguage   string
+	PublicURL          string
+	MemberEmails       []string
+	LinkSecret         string
+	LinkExpiry         time.Duration
 	EmailEnabled       bool
 	EmailProvider      string
 	SendGridAPIKey     string
@@ -42,6 +67,31 @@ func New() *Config {
 		MaxUploadSize:      getEnvAsInt64("MAX_UPLOAD_SIZE", 16<<20), // 16MB
 		FileTokenExpiry:    getEnvAsDuration("FILE_TOKEN_EXPIRY", 24*time.Hour),
 		SheetName:          getEnv("SHEET_NAME", "docházka správců týmu"),
+		EventTypesInclude:  getEnvAsStringSlice("EVENT_TYPES_INCLUDE", nil),
+		EventTypesExclude:  getEnvAsStringSlice("EVENT_TYPES_EXCLUDE", nil),
+		AttendanceAccepted: getEnvAsStringSlice("ATTENDANCE_VALUES", nil),
+		AttendanceDeclined: getEnvAsStringSlice("ATTENDANCE_DECLINED_VALUES", nil),
+		DateLayouts:        getEnvAsStringSlice("DATE_LAYOUTS", nil),
+		MaxDailyHours:      getEnvAsFloat64("MAX_DAILY_HOURS", 12),
+		SplitOvernightRows: getEnvAsBool("SPLIT_OVERNIGHT_ROWS", true),
+		MultiDayDailyCap:   getEnvAsDuration("MULTI_DAY_DAILY_CAP", 0),
+		MultiDayDayStart:   getEnvAsDuration("MULTI_DAY_DAY_START", 8*time.Hour),
+		Timezone:           getEnv("TIMEZONE", "Europe/Prague"),
+		HolidayCountry:     getEnv("HOLIDAY_COUNTRY", "CZ"),
+		HolidayExtraDays:   getEnvAsStringSlice("HOLIDAY_EXTRA_DAYS", nil),
+		DataDir:            getEnv("DATA_DIR", ""),
+		DraftExpiry:        getEnvAsDuration("DRAFT_EXPIRY", 30*24*time.Hour),
+		CoordinatorUser:    getEnv("COORDINATOR_USERNAME", "coordinator"),
+		CoordinatorPass:    getEnv("COORDINATOR_PASSWORD", ""),
+		AdminUser:          getEnv("ADMIN_USERNAME", "admin"),
+		AdminPass:          getEnv("ADMIN_PASSWORD", ""),
+		TenantsFile:        getEnv("TENANTS_FILE", ""),
+		ReminderSchedule:   getEnv("REMINDER_SCHEDULE", ""),
+		ReminderLanguage:   getEnv("REMINDER_LANGUAGE", "cs"),
+		PublicURL:          getEnv("PUBLIC_URL", "http://localhost:8080"),
+		MemberEmails:       getEnvAsStringSlice("MEMBER_EMAILS", nil),
+		LinkSecret:         getEnv("LINK_SECRET", ""),
+		LinkExpiry:         getEnvAsDuration("LINK_EXPIRY", 14*24*time.Hour),
 		EmailEnabled:       getEnvAsBool("EMAIL_ENABLED", false),
 		EmailProvider:      getEnv("EMAIL_PROVIDER", "sendgrid"), // Default to SendGrid
 		SendGridAPIKey:     getEnv("SENDGRID_API_KEY", ""),
@@ -77,6 +127,15 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvAsFloat64(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if d, err := time.ParseDuration(value); err == nil {
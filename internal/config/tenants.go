@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+// Tenant IDs name the tenant's data directory, so they are kept simple
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadTenants reads the tenants sharing the deployment from a JSON file
+// holding a list of tenants. Requests matching none of them are served by the
+// default tenant configured through the environment.
+func LoadTenants(path string) ([]models.Tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	var tenants []models.Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file: %w", err)
+	}
+
+	// Report mappings are read again over the default mapping, so a tenant
+	// only lists the cells that differ
+	var mappings []struct {
+		Mapping json.RawMessage `json:"mapping"`
+	}
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file: %w", err)
+	}
+
+	ids := make(map[string]bool)
+	hosts := make(map[string]string)
+	prefixes := make(map[string]string)
+	for i := range tenants {
+		tenant := &tenants[i]
+
+		if !tenantIDPattern.MatchString(tenant.ID) {
+			return nil, fmt.Errorf("tenant %d: invalid id %q", i+1, tenant.ID)
+		}
+		if ids[tenant.ID] {
+			return nil, fmt.Errorf("tenant %q is listed twice", tenant.ID)
+		}
+		ids[tenant.ID] = true
+
+		tenant.PathPrefix = strings.TrimSuffix(strings.TrimSpace(tenant.PathPrefix), "/")
+		if tenant.PathPrefix != "" {
+			if !strings.HasPrefix(tenant.PathPrefix, "/") {
+				return nil, fmt.Errorf("tenant %q: path prefix %q must start with /", tenant.ID, tenant.PathPrefix)
+			}
+			if other, ok := prefixes[tenant.PathPrefix]; ok {
+				return nil, fmt.Errorf("tenants %q and %q share the path prefix %q", other, tenant.ID, tenant.PathPrefix)
+			}
+			prefixes[tenant.PathPrefix] = tenant.ID
+		}
+
+		for j, host := range tenant.Hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if other, ok := hosts[host]; ok {
+				return nil, fmt.Errorf("tenants %q and %q share the host %q", other, tenant.ID, host)
+			}
+			hosts[host] = tenant.ID
+			tenant.Hosts[j] = host
+		}
+
+		if len(tenant.Hosts) == 0 && tenant.PathPrefix == "" {
+			return nil, fmt.Errorf("tenant %q has neither hosts nor a path prefix", tenant.ID)
+		}
+
+		if tenant.Mapping != nil {
+			mapping := services.DefaultReportMapping()
+			if err := json.Unmarshal(mappings[i].Mapping, &mapping); err != nil {
+				return nil, fmt.Errorf("tenant %q: failed to parse mapping: %w", tenant.ID, err)
+			}
+			if issues := services.ValidateReportMapping(mapping); len(issues) > 0 {
+				return nil, fmt.Errorf("tenant %q: invalid mapping: %s %v", tenant.ID, issues[0].Code, issues[0].Args)
+			}
+			tenant.Mapping = &mapping
+		}
+	}
+
+	return tenants, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"timesheet-filler/internal/services"
+)
+
+func writeTenantsFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write tenants file: %v", err)
+	}
+	return path
+}
+
+func TestLoadTenants(t *testing.T) {
+	path := writeTenantsFile(t, `[
+		{
+			"id": "partner",
+			"hosts": ["Partner.Example.com "],
+			"pathPrefix": "/partner/",
+			"sheetName": "docházka",
+			"emailRecipients": ["coach@partner.example.com"],
+			"branding": {"accentColor": "#0055a4"},
+			"translations": {"cs": {"app_title": "Výkaz partnera"}},
+			"mapping": {"targetSheet": "timesheet", "startRow": 6}
+		}
+	]`)
+
+	tenants, err := LoadTenants(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tenants) != 1 {
+		t.Fatalf("Expected 1 tenant, got %d", len(tenants))
+	}
+	tenant := tenants[0]
+	if tenant.Hosts[0] != "partner.example.com" || tenant.PathPrefix != "/partner" {
+		t.Errorf("Expected the host and prefix to be normalized, got %q and %q", tenant.Hosts, tenant.PathPrefix)
+	}
+	if tenant.SheetName != "docházka" || tenant.Branding.AccentColor != "#0055a4" || tenant.Translations["cs"]["app_title"] != "Výkaz partnera" {
+		t.Errorf("Expected the tenant settings to be read, got %+v", tenant)
+	}
+
+	// The cells the mapping leaves out keep their defaults
+	if tenant.Mapping == nil || tenant.Mapping.TargetSheet != "timesheet" || tenant.Mapping.StartRow != 6 ||
+		tenant.Mapping.MaxRows != services.MaxReportRows || tenant.Mapping.DateColumn != "A" {
+		t.Errorf("Expected the mapping to be merged over the default one, got %+v", tenant.Mapping)
+	}
+}
+
+func TestLoadTenantsErrors(t *testing.T) {
+	tests := map[string]string{
+		"invalid id":        `[{"id": "../partner", "pathPrefix": "/partner"}]`,
+		"duplicate id":      `[{"id": "partner", "pathPrefix": "/a"}, {"id": "partner", "pathPrefix": "/b"}]`,
+		"relative prefix":   `[{"id": "partner", "pathPrefix": "partner"}]`,
+		"shared prefix":     `[{"id": "a", "pathPrefix": "/club"}, {"id": "b", "pathPrefix": "/club/"}]`,
+		"shared host":       `[{"id": "a", "hosts": ["club.example.com"]}, {"id": "b", "hosts": ["CLUB.example.com"]}]`,
+		"unreachable":       `[{"id": "partner"}]`,
+		"no entry rows":     `[{"id": "partner", "pathPrefix": "/partner", "mapping": {"maxRows": -1}}]`,
+		"invalid cell":      `[{"id": "partner", "pathPrefix": "/partner", "mapping": {"firstNameCell": "3B"}}]`,
+		"not a tenant list": `{"id": "partner"}`,
+	}
+
+	for name, content := range tests {
+		if _, err := LoadTenants(writeTenantsFile(t, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -5,3 +5,9 @@ type Key string
 
 // LanguageKey is the context key for the language value
 const LanguageKey Key = "language"
+
+// ClientIDKey is the context key for the browser client identifier
+const ClientIDKey Key = "client_id"
+
+// TenantKey is the context key for the ID of the tenant serving the request
+const TenantKey Key = "tenant"
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"timesheet-filler/internal/services"
+	"timesheet-filler/internal/utils"
+)
+
+type CalendarHandler struct {
+	excelService *services.ExcelService
+	icalService  *services.ICalService
+	fileStore    *services.FileStore
+}
+
+func NewCalendarHandler(
+	excelService *services.ExcelService,
+	icalService *services.ICalService,
+	fileStore *services.FileStore,
+) *CalendarHandler {
+	return &CalendarHandler{
+		excelService: excelService,
+		icalService:  icalService,
+		fileStore:    fileStore,
+	}
+}
+
+// ExportHandler returns the entries of a member and month as an .ics file.
+// A POST from the edit page exports the rows as currently edited, a GET
+// exports the rows extracted from the uploaded file.
+func (h *CalendarHandler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	fileToken := r.FormValue("fileToken")
+	month, err := strconv.Atoi(r.FormValue("month"))
+	if name == "" || fileToken == "" || err != nil || month < 1 || month > 12 {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	tableData := tableRowsFromForm(r)
+	if r.Method == http.MethodGet {
+		fileData, ok := h.fileStore.GetFileData(fileToken)
+		if !ok {
+			http.Error(w, "File Not Found", http.StatusNotFound)
+			return
+		}
+		if !sessionAllows(fileData, name, month) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		tableData, _, err = extractTableData(h.excelService, h.icalService, fileData, name, month, extractOptionsFromForm(r))
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			log.Printf("Error extracting calendar data: %v", err)
+			return
+		}
+	}
+
+	firstname, lastname := h.excelService.SplitName(name)
+	filename := fmt.Sprintf("Gorily_kalendar_%02d%d_%s_%s.ics",
+		month,
+		services.ReportYear(tableData),
+		utils.RemoveDiacritics(firstname),
+		utils.RemoveDiacritics(lastname),
+	)
+	filename = utils.SanitizeFilename(filename)
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if _, err := w.Write(h.icalService.Encode(name, tableData)); err != nil {
+		log.Printf("Error sending calendar: %v", err)
+	}
+}
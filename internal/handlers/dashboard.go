@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+type DashboardHandler struct {
+	excelService     *services.ExcelService
+	dashboardService *services.DashboardService
+	fileStore        *services.FileStore
+	templateService  *services.TemplateService
+	maxUploadSize    int64
+}
+
+func NewDashboardHandler(
+	excelService *services.ExcelService,
+	dashboardService *services.DashboardService,
+	fileStore *services.FileStore,
+	templateService *services.TemplateService,
+	maxUploadSize int64,
+) *DashboardHandler {
+	return &DashboardHandler{
+		excelService:     excelService,
+		dashboardService: dashboardService,
+		fileStore:        fileStore,
+		templateService:  templateService,
+		maxUploadSize:    maxUploadSize,
+	}
+}
+
+// DashboardHandler shows coordinators which members have generated, emailed
+// or submitted their report for a period. A POST uploads the attendance
+// export whose member list the dashboard is built from.
+func (h *DashboardHandler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method == http.MethodPost {
+		h.uploadExport(w, r, lang)
+		return
+	}
+
+	query := r.URL.Query()
+	tmplData := models.DashboardTemplateData{
+		FileToken: query.Get("fileToken"),
+		Period:    query.Get("period"),
+		Status:    query.Get("status"),
+		Team:      query.Get("team"),
+		Search:    query.Get("search"),
+	}
+
+	if tmplData.FileToken == "" {
+		h.templateService.RenderTemplate(w, "dashboard.html", tmplData, http.StatusOK, lang)
+		return
+	}
+
+	rows, err := h.buildRows(tmplData.FileToken, tmplData.Period)
+	if err != nil {
+		tmplData.FileToken = ""
+		tmplData.Error = err.Error()
+		h.templateService.RenderTemplate(w, "dashboard.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+
+	tmplData.Counts = make(map[string]int)
+	for _, row := range rows {
+		tmplData.Counts[row.Status]++
+	}
+	tmplData.Teams = services.DashboardTeams(rows)
+	tmplData.Rows = services.FilterDashboard(rows, tmplData.Status, tmplData.Team, tmplData.Search)
+
+	h.templateService.RenderTemplate(w, "dashboard.html", tmplData, http.StatusOK, lang)
+}
+
+// CSVHandler exports the filtered dashboard as CSV
+func (h *DashboardHandler) CSVHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	period := query.Get("period")
+
+	rows, err := h.buildRows(query.Get("fileToken"), period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows = services.FilterDashboard(rows, query.Get("status"), query.Get("team"), query.Get("search"))
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "dashboard_"+period+".csv"))
+
+	// A byte order mark lets spreadsheet applications detect UTF-8 names
+	w.Write([]byte("\xef\xbb\xbf"))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"member", "team", "events", "hours", "versions", "last_generated", "emailed", "submission", "status"})
+	for _, row := range rows {
+		lastGenerated := ""
+		if !row.LastGenerated.IsZero() {
+			lastGenerated = row.LastGenerated.Format(time.RFC3339)
+		}
+		writer.Write([]string{
+			row.Name,
+			row.Team,
+			strconv.Itoa(row.Events),
+			strconv.FormatFloat(row.Hours, 'f', 2, 64),
+			strconv.Itoa(row.Versions),
+			lastGenerated,
+			strconv.FormatBool(row.Emailed),
+			row.SubmissionStatus,
+			row.Status,
+		})
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		log.Printf("Error writing dashboard CSV: %v", err)
+	}
+}
+
+// buildRows builds the dashboard of a stored export for a "YYYY-MM" period
+func (h *DashboardHandler) buildRows(fileToken, period string) ([]models.DashboardRow, error) {
+	fileData, ok := h.fileStore.GetFileData(fileToken)
+	if !ok {
+		return nil, fmt.Errorf("invalid session, please upload the export again")
+	}
+
+	year, month := parsePeriod(period)
+	if month == 0 {
+		return nil, fmt.Errorf("invalid period %q", period)
+	}
+
+	return h.dashboardService.Build(fileData.Data, parseContext(fileData), fileData.Names, year, month)
+}
+
+func (h *DashboardHandler) uploadExport(w http.ResponseWriter, r *http.Request, lang string) {
+	renderError := func(status int, message string) {
+		tmplData := models.DashboardTemplateData{
+			BaseTemplateData: models.BaseTemplateData{Error: message},
+		}
+		h.templateService.RenderTemplate(w, "dashboard.html", tmplData, status, lang)
+	}
+
+	if err := r.ParseMultipartForm(h.maxUploadSize); err != nil {
+		renderError(http.StatusBadRequest, "Bad Request: Unable to parse form data.")
+		return
+	}
+
+	file, _, err := r.FormFile("excelFile")
+	if err != nil {
+		renderError(http.StatusBadRequest, "Bad Request: Unable to retrieve file.")
+		return
+	}
+	defer file.Close()
+
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, file); err != nil {
+		renderError(http.StatusInternalServerError, "Internal Server Error: Unable to read file.")
+		return
+	}
+
+	// When the configured sheet is missing, read the sheet recognised as the
+	// attendance export. The chosen sheet is kept with the export, so
+	// personal links and reminders read the same one.
+	files := []models.SourceFile{{Data: buf.Bytes()}}
+	var pc services.ParseContext
+	names, monthsInt, err := h.excelService.ParseSourcesForNamesAndMonths(files, pc)
+	var snfErr services.SheetNotFoundError
+	if errors.As(err, &snfErr) {
+		if sheet, detectErr := h.excelService.DetectSourceSheet(buf.Bytes()); detectErr == nil {
+			log.Printf("Sheet '%s' not found, reading sheet '%s' recognised by its columns", snfErr.SheetName, sheet)
+			pc = services.ParseContext{Sheets: []string{sheet}}
+			names, monthsInt, err = h.excelService.ParseSourcesForNamesAndMonths(files, pc)
+		}
+	}
+	if err != nil {
+		renderError(http.StatusBadRequest, "Unable to parse Excel file: "+err.Error())
+		return
+	}
+
+	var months []string
+	for _, m := range monthsInt {
+		months = append(months, strconv.Itoa(m))
+	}
+	fileToken := h.fileStore.StoreFileDataEntry(models.FileData{
+		Data:       buf.Bytes(),
+		Kind:       models.SourceKindExcel,
+		Names:      names,
+		Months:     months,
+		SheetNames: pc.Sheets,
+	})
+	h.dashboardService.SaveExport(buf.Bytes(), pc)
+
+	// Default to the latest month of the export, assuming it is not in the future
+	now := time.Now()
+	year, month := now.Year(), int(now.Month())
+	if len(monthsInt) > 0 {
+		month = monthsInt[len(monthsInt)-1]
+		if month > int(now.Month()) {
+			year--
+		}
+	}
+
+	redirect := url.Values{}
+	redirect.Set("fileToken", fileToken)
+	redirect.Set("period", fmt.Sprintf("%d-%02d", year, month))
+	http.Redirect(w, r, "/coordinator/dashboard?"+redirect.Encode(), http.StatusSeeOther)
+}
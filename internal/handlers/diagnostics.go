@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+type DiagnosticsHandler struct {
+	excelService    *services.ExcelService
+	fileStore       *services.FileStore
+	templateService *services.TemplateService
+}
+
+func NewDiagnosticsHandler(excelService *services.ExcelService, fileStore *services.FileStore, templateService *services.TemplateService) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		excelService:    excelService,
+		fileStore:       fileStore,
+		templateService: templateService,
+	}
+}
+
+// DiagnosticsHandler shows how an uploaded attendance export was read and
+// which rows were skipped. With format=json the report is returned as JSON.
+func (h *DiagnosticsHandler) DiagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	fileToken := r.URL.Query().Get("fileToken")
+	asJSON := r.URL.Query().Get("format") == "json"
+
+	fileData, err := h.sessionSource(fileToken)
+	if err != nil {
+		if asJSON {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmplData := models.BaseTemplateData{Error: err.Error()}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+
+	diagnostics, err := h.excelService.Diagnose(sourceFiles(fileData), parseContext(fileData))
+	if err != nil {
+		log.Printf("Error diagnosing uploaded file: %v", err)
+		if asJSON {
+			http.Error(w, "Unable to read the uploaded file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tmplData := models.BaseTemplateData{Error: "Unable to read the uploaded file: " + err.Error()}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusInternalServerError, lang)
+		return
+	}
+
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(diagnostics)
+		return
+	}
+
+	tmplData := models.DiagnosticsTemplateData{
+		FileToken:   fileToken,
+		Diagnostics: diagnostics,
+	}
+	h.templateService.RenderTemplate(w, "diagnostics.html", tmplData, http.StatusOK, lang)
+}
+
+// AnnotatedHandler downloads one of the uploaded exports, chosen by its index
+// in the file parameter, with its skipped rows highlighted
+func (h *DiagnosticsHandler) AnnotatedHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	fileData, err := h.sessionSource(r.URL.Query().Get("fileToken"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	files := sourceFiles(fileData)
+
+	file, _ := strconv.Atoi(r.URL.Query().Get("file"))
+	if file < 0 || file >= len(files) {
+		http.Error(w, "Bad Request: Unknown file", http.StatusBadRequest)
+		return
+	}
+
+	translator := h.templateService.GetTranslator()
+	labels := services.AnnotationLabels{
+		Header:  translator.Translate("diagnostics_reason", lang),
+		Reasons: make(map[string]string),
+	}
+	for _, reason := range []string{
+		services.ExcludedMissingMember,
+		services.ExcludedInvalidDate,
+		services.ExcludedDuplicate,
+		services.ExcludedEventType,
+		services.ExcludedDeclined,
+		services.ExcludedUnconfirmed,
+	} {
+		labels.Reasons[reason] = translator.Translate("skip_reason_"+reason, lang)
+	}
+
+	annotated, err := h.excelService.AnnotateSource(files, parseContext(fileData), file, labels)
+	if err != nil {
+		log.Printf("Error annotating uploaded file: %v", err)
+		http.Error(w, "Unable to annotate the uploaded file", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "annotated.xlsx"
+	if files[file].Filename != "" {
+		filename = "annotated_" + files[file].Filename
+	}
+	w.Header().Set("Content-Type", contentTypeForFile(filename))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if _, err := w.Write(annotated); err != nil {
+		log.Printf("Error sending annotated file: %v", err)
+	}
+}
+
+// sessionSource returns the stored attendance exports of a file token
+func (h *DiagnosticsHandler) sessionSource(fileToken string) (models.FileData, error) {
+	fileData, ok := h.fileStore.GetFileData(fileToken)
+	if !ok {
+		return fileData, fmt.Errorf("invalid session, please upload the file again")
+	}
+	if fileData.Kind != models.SourceKindExcel {
+		return fileData, fmt.Errorf("upload details are only available for attendance exports")
+	}
+	if fileData.Scope != nil {
+		return fileData, fmt.Errorf("upload details are not available for personal links")
+	}
+	return fileData, nil
+}
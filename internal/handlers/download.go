@@ -39,7 +39,7 @@ func (h *DownloadHandler) DownloadHandler(w http.ResponseWriter, r *http.Request
 
 	log.Printf("Found file for download: %s (size: %d bytes", fileEntry.Filename, len(fileEntry.Data))
 
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Type", contentTypeForFile(fileEntry.Filename))
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileEntry.Filename))
 
 	_, err := w.Write(fileEntry.Data)
@@ -49,3 +49,11 @@ func (h *DownloadHandler) DownloadHandler(w http.ResponseWriter, r *http.Request
 
 	h.fileStore.DeleteTempFile(token)
 }
+
+// contentTypeForFile returns the MIME type of a generated report file
+func contentTypeForFile(filename string) string {
+	if strings.HasSuffix(strings.ToLower(filename), ".pdf") {
+		return "application/pdf"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
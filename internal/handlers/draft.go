@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+	"timesheet-filler/internal/utils"
+)
+
+type DraftHandler struct {
+	draftStore *services.DraftStore
+}
+
+func NewDraftHandler(draftStore *services.DraftStore) *DraftHandler {
+	return &DraftHandler{
+		draftStore: draftStore,
+	}
+}
+
+// SaveHandler stores the rows currently shown on the edit page. It is called
+// in the background by the page whenever the table changes.
+func (h *DraftHandler) SaveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	clientID := clientIDFromRequest(r)
+	name := r.FormValue("name")
+	month, err := utils.ParseMonth(r.FormValue("month"))
+	year, yearErr := strconv.Atoi(r.FormValue("year"))
+	if clientID == "" || name == "" || err != nil || yearErr != nil || year < 1 {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	draft := h.draftStore.Save(models.Draft{
+		ClientID:  clientID,
+		Name:      name,
+		Year:      year,
+		Month:     strconv.Itoa(month),
+		FileToken: r.FormValue("fileToken"),
+		TableData: tableRowsFromForm(r),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.DraftSaveResponse{
+		SavedAt: draft.UpdatedAt.Format("15:04:05"),
+	})
+}
+
+// clientIDFromRequest returns the browser identifier set by the client middleware
+func clientIDFromRequest(r *http.Request) string {
+	if clientID, ok := r.Context().Value(contextkeys.ClientIDKey).(string); ok {
+		return clientID
+	}
+	return ""
+}
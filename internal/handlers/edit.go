@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"timesheet-filler/internal/contextkeys"
 	"timesheet-filler/internal/metrics"
@@ -11,20 +12,32 @@ import (
 	"timesheet-filler/internal/utils"
 )
 
+// Draft actions submitted from the edit page
+const (
+	draftActionDiscard = "discard"
+	draftActionSource  = "source"
+)
+
 type EditHandler struct {
 	excelService    *services.ExcelService
+	icalService     *services.ICalService
 	fileStore       *services.FileStore
+	draftStore      *services.DraftStore
 	templateService *services.TemplateService
 }
 
 func NewEditHandler(
 	excelService *services.ExcelService,
+	icalService *services.ICalService,
 	fileStore *services.FileStore,
+	draftStore *services.DraftStore,
 	templateService *services.TemplateService,
 ) *EditHandler {
 	return &EditHandler{
 		excelService:    excelService,
+		icalService:     icalService,
 		fileStore:       fileStore,
+		draftStore:      draftStore,
 		templateService: templateService,
 	}
 }
@@ -38,7 +51,8 @@ func (h *EditHandler) EditHandler(w http.ResponseWriter, r *http.Request) {
 		lang = "en"
 	}
 
-	if r.Method != http.MethodPost {
+	// Personal links open the edit page with a GET
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -47,6 +61,7 @@ func (h *EditHandler) EditHandler(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	monthStr := r.FormValue("month")
 	fileToken := r.FormValue("fileToken")
+	extractOptions := extractOptionsFromForm(r)
 
 	if name == "" || monthStr == "" || fileToken == "" {
 		tmplData := models.BaseTemplateData{
@@ -59,9 +74,47 @@ func (h *EditHandler) EditHandler(w http.ResponseWriter, r *http.Request) {
 	m := metrics.GetMetrics()
 	m.RecordPersonSelection(name)
 
-	// Retrieve the stored file data
+	clientID := clientIDFromRequest(r)
+	draftMonth := monthStr
+	if month, err := utils.ParseMonth(monthStr); err == nil {
+		draftMonth = strconv.Itoa(month)
+	}
+
+	// Retrieve the stored file data. A saved draft can still be edited after
+	// the uploaded file has expired.
 	fileDataStruct, ok := h.fileStore.GetFileData(fileToken)
-	if !ok {
+
+	// Drafts are kept per report year: the one passed along with a draft or
+	// the edit page, that of a personal link or else the year of the
+	// member's rows in the source, which are then only read once
+	year, _ := strconv.Atoi(r.FormValue("year"))
+	var sourceRows []models.TableRow
+	var excluded map[string]int
+	var sourceErr error
+	extracted := false
+	if year == 0 && ok {
+		if fileDataStruct.Scope != nil {
+			year = fileDataStruct.Scope.Year
+		} else if month, err := utils.ParseMonth(monthStr); err == nil {
+			sourceRows, excluded, sourceErr = extractTableData(h.excelService, h.icalService, fileDataStruct, name, month, extractOptions)
+			extracted = true
+			year = services.ReportYear(sourceRows)
+		}
+	}
+
+	// Handle the draft actions offered on the edit page. Discarding removes
+	// the draft, restoring from source only ignores it for this page load.
+	draftAction := r.FormValue("draftAction")
+	if draftAction == draftActionDiscard {
+		h.draftStore.Delete(clientID, name, year, draftMonth)
+	}
+
+	draft, hasDraft := h.draftStore.Get(clientID, name, year, draftMonth)
+	if draftAction == draftActionSource {
+		hasDraft = false
+	}
+
+	if !ok && !hasDraft {
 		tmplData := models.BaseTemplateData{
 			Error: "Invalid session. Please re-upload your file.",
 		}
@@ -79,28 +132,49 @@ func (h *EditHandler) EditHandler(w http.ResponseWriter, r *http.Request) {
 			FileToken:    fileToken,
 			Names:        fileDataStruct.Names,
 			Months:       fileDataStruct.Months,
+			EventTypes:   fileDataStruct.EventTypes,
 			DefaultMonth: monthStr,
+			Attendance:   fileDataStruct.Kind == models.SourceKindExcel || fileDataStruct.Kind == "",
 		}
 		h.templateService.RenderTemplate(w, "select.html", tmplData, http.StatusBadRequest, lang)
 		return
 	}
 
-	// Extract data from the uploaded Excel file
-	tableData, err := h.excelService.ExtractTableData(fileDataStruct.Data, name, month)
-	if err != nil {
-		tmplData := models.SelectTemplateData{
-			BaseTemplateData: models.BaseTemplateData{
-				Error: fmt.Sprintf("Failed to extract data: %v", err),
-			},
-			FileToken:    fileToken,
-			Names:        fileDataStruct.Names,
-			Months:       fileDataStruct.Months,
-			DefaultMonth: monthStr,
+	if !sessionAllows(fileDataStruct, name, month) {
+		tmplData := models.BaseTemplateData{
+			Error: "This link only opens the timesheet it was sent for.",
 		}
-		h.templateService.RenderTemplate(w, "select.html", tmplData, http.StatusInternalServerError, lang)
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusForbidden, lang)
 		return
 	}
 
+	var tableData []models.TableRow
+	if hasDraft {
+		tableData = draft.TableData
+		excluded = nil
+	} else {
+		// Extract data from the uploaded Excel or calendar file
+		if !extracted {
+			sourceRows, excluded, sourceErr = extractTableData(h.excelService, h.icalService, fileDataStruct, name, month, extractOptions)
+		}
+		tableData, err = sourceRows, sourceErr
+		if err != nil {
+			tmplData := models.SelectTemplateData{
+				BaseTemplateData: models.BaseTemplateData{
+					Error: fmt.Sprintf("Failed to extract data: %v", err),
+				},
+				FileToken:    fileToken,
+				Names:        fileDataStruct.Names,
+				Months:       fileDataStruct.Months,
+				EventTypes:   fileDataStruct.EventTypes,
+				DefaultMonth: monthStr,
+				Attendance:   fileDataStruct.Kind == models.SourceKindExcel || fileDataStruct.Kind == "",
+			}
+			h.templateService.RenderTemplate(w, "select.html", tmplData, http.StatusInternalServerError, lang)
+			return
+		}
+	}
+
 	// If no data was found, initialize with an empty row
 	if len(tableData) == 0 {
 		tableData = []models.TableRow{
@@ -113,11 +187,23 @@ func (h *EditHandler) EditHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if year == 0 {
+		year = services.ReportYear(tableData)
+	}
+
 	tmplData := models.EditTemplateData{
-		FileToken: fileToken,
-		Name:      name,
-		Month:     monthStr,
-		TableData: tableData,
+		FileToken:        fileToken,
+		Name:             name,
+		Year:             year,
+		Month:            monthStr,
+		TableData:        tableData,
+		MaxRows:          h.excelService.ReportMappingFor(tableData).MaxRows,
+		HasDraft:         hasDraft,
+		DraftSaved:       draft.UpdatedAt,
+		Holidays:         holidaysOfRows(h.excelService.Holidays(), tableData),
+		EventTypes:       extractOptions.EventTypes,
+		IncludeTentative: extractOptions.IncludeTentative,
+		Excluded:         excluded,
 	}
 
 	h.templateService.RenderTemplate(w, "edit.html", tmplData, http.StatusOK, lang)
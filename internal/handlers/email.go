@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"timesheet-filler/internal/contextkeys"
@@ -11,9 +12,18 @@ import (
 	"timesheet-filler/internal/services"
 )
 
+// Attachment formats selectable on the download page
+const (
+	attachmentFormatXLSX = "xlsx"
+	attachmentFormatPDF  = "pdf"
+	attachmentFormatBoth = "both"
+)
+
 type EmailHandler struct {
 	fileStore       *services.FileStore
 	emailService    *services.EmailService
+	versionStore    *services.VersionStore
+	profileStore    *services.ProfileStore
 	templateService *services.TemplateService
 	emailEnabled    bool
 }
@@ -21,12 +31,16 @@ type EmailHandler struct {
 func NewEmailHandler(
 	fileStore *services.FileStore,
 	emailService *services.EmailService,
+	versionStore *services.VersionStore,
+	profileStore *services.ProfileStore,
 	templateService *services.TemplateService,
 	emailEnabled bool,
 ) *EmailHandler {
 	return &EmailHandler{
 		fileStore:       fileStore,
 		emailService:    emailService,
+		versionStore:    versionStore,
+		profileStore:    profileStore,
 		templateService: templateService,
 		emailEnabled:    emailEnabled,
 	}
@@ -67,8 +81,17 @@ func (h *EmailHandler) SendEmailHandler(w http.ResponseWriter, r *http.Request)
 	fileName := r.FormValue("fileName")
 	name := r.FormValue("name")
 	month := r.FormValue("month")
+	pdfDownloadToken := r.FormValue("pdfDownloadToken")
+	pdfFileName := r.FormValue("pdfFileName")
+	versionID := r.FormValue("versionID")
+	versionNumber, _ := strconv.Atoi(r.FormValue("versionNumber"))
+	year, _ := strconv.Atoi(r.FormValue("year"))
 	userEmail := r.FormValue("userEmail")
 	sendToSelf := r.FormValue("sendToSelf") == "true"
+	attachmentFormat := r.FormValue("attachmentFormat")
+	if attachmentFormat == "" {
+		attachmentFormat = attachmentFormatXLSX
+	}
 
 	// Validate inputs
 	if fileToken == "" || downloadToken == "" || fileName == "" {
@@ -85,30 +108,63 @@ func (h *EmailHandler) SendEmailHandler(w http.ResponseWriter, r *http.Request)
 			BaseTemplateData: models.BaseTemplateData{
 				Error: "Please enter a valid email address",
 			},
-			DownloadToken: downloadToken,
-			FileName:      fileName,
-			EmailEnabled:  h.emailEnabled,
+			DownloadToken:    downloadToken,
+			FileName:         fileName,
+			PDFDownloadToken: pdfDownloadToken,
+			PDFFileName:      pdfFileName,
+			FileToken:        fileToken,
+			Name:             name,
+			Month:            month,
+			EmailEnabled:     h.emailEnabled,
+			VersionID:        versionID,
+			VersionNumber:    versionNumber,
+			Year:             year,
 			EmailOptions: models.EmailOptions{
-				SendToSelf: sendToSelf,
-				UserEmail:  userEmail,
+				SendToSelf:       sendToSelf,
+				UserEmail:        userEmail,
+				AttachmentFormat: attachmentFormat,
 			},
 		}
 		h.templateService.RenderTemplate(w, "download.html", tmplData, http.StatusBadRequest, lang)
 		return
 	}
 
-	// Get the file data
-	fileEntry, ok := h.fileStore.GetTempFile(downloadToken)
-	if !ok {
-		tmplData := models.BaseTemplateData{
-			Error: "File not found. It may have expired.",
+	// Collect the files to attach in the requested format
+	var attachments []*services.EmailAttachment
+	requested := []struct {
+		format string
+		token  string
+	}{
+		{attachmentFormatXLSX, downloadToken},
+		{attachmentFormatPDF, pdfDownloadToken},
+	}
+	for _, req := range requested {
+		if attachmentFormat != req.format && attachmentFormat != attachmentFormatBoth {
+			continue
 		}
-		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusNotFound, lang)
-		return
+
+		fileEntry, ok := h.fileStore.GetTempFile(req.token)
+		if !ok {
+			tmplData := models.BaseTemplateData{
+				Error: "File not found. It may have expired.",
+			}
+			h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusNotFound, lang)
+			return
+		}
+
+		attachments = append(attachments, &services.EmailAttachment{
+			FileName:    fileEntry.Filename,
+			ContentType: contentTypeForFile(fileEntry.Filename),
+			Data:        fileEntry.Data,
+		})
 	}
 
-	// Prepare email recipients
+	// Prepare email recipients, the member's own ones when the profile has
+	// them
 	recipients := h.emailService.DefaultTos
+	if profile, ok := h.profileStore.Get(name); ok && len(profile.Recipients) > 0 {
+		recipients = profile.Recipients
+	}
 	var ccList []string
 
 	// Add user to CC if requested
@@ -125,22 +181,27 @@ func (h *EmailHandler) SendEmailHandler(w http.ResponseWriter, r *http.Request)
 		bodyTemplate,
 		name, month)
 
-	// Prepare attachment
-	attachment := &services.EmailAttachment{
-		FileName:    fileName,
-		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
-		Data:        fileEntry.Data,
-	}
-
 	// Send the email
-	err := h.emailService.SendEmailWithAttachment(subject, body, recipients, ccList, attachment)
+	err := h.emailService.SendEmailWithAttachments(subject, body, recipients, ccList, attachments)
 
 	// Prepare template data
 	tmplData := models.DownloadTemplateData{
-		DownloadToken: downloadToken,
-		FileName:      fileName,
-		EmailEnabled:  h.emailEnabled,
-		EmailSent:     err == nil,
+		DownloadToken:    downloadToken,
+		FileName:         fileName,
+		PDFDownloadToken: pdfDownloadToken,
+		PDFFileName:      pdfFileName,
+		FileToken:        fileToken,
+		Name:             name,
+		Month:            month,
+		EmailEnabled:     h.emailEnabled,
+		EmailSent:        err == nil,
+		VersionNumber:    versionNumber,
+		Year:             year,
+	}
+
+	// Remember which version was sent, so it stands out in the history
+	if err == nil && versionID != "" {
+		h.versionStore.MarkEmailed(versionID)
 	}
 
 	if err != nil {
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+// PersonalLinkHandler lets coordinators hand out personal links, each opening
+// a member's own timesheet for one month from the latest attendance export
+// without exposing the other members in it
+type PersonalLinkHandler struct {
+	excelService     *services.ExcelService
+	dashboardService *services.DashboardService
+	fileStore        *services.FileStore
+	linkService      *services.PersonalLinkService
+	contacts         *services.MemberContacts
+	emailService     *services.EmailService
+	templateService  *services.TemplateService
+	emailEnabled     bool
+}
+
+func NewPersonalLinkHandler(
+	excelService *services.ExcelService,
+	dashboardService *services.DashboardService,
+	fileStore *services.FileStore,
+	linkService *services.PersonalLinkService,
+	contacts *services.MemberContacts,
+	emailService *services.EmailService,
+	templateService *services.TemplateService,
+	emailEnabled bool,
+) *PersonalLinkHandler {
+	return &PersonalLinkHandler{
+		excelService:     excelService,
+		dashboardService: dashboardService,
+		fileStore:        fileStore,
+		linkService:      linkService,
+		contacts:         contacts,
+		emailService:     emailService,
+		templateService:  templateService,
+		emailEnabled:     emailEnabled,
+	}
+}
+
+// ListHandler shows the personal links of the members with events in a
+// "YYYY-MM" period, the previous month by default
+func (h *PersonalLinkHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	query := r.URL.Query()
+	tmplData := models.PersonalLinksTemplateData{
+		Period:       query.Get("period"),
+		EmailEnabled: h.emailEnabled && h.emailService.IsConfigured(),
+	}
+	if tmplData.Period == "" {
+		tmplData.Period = time.Now().AddDate(0, -1, 0).Format("2006-01")
+	}
+	if sent, err := strconv.Atoi(query.Get("sent")); err == nil {
+		tmplData.Sent = sent
+		tmplData.ShowSent = true
+	}
+
+	links, err := h.issueLinks(tmplData.Period)
+	if err != nil {
+		tmplData.Error = err.Error()
+		h.templateService.RenderTemplate(w, "personal_links.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+	tmplData.Links = links
+
+	h.templateService.RenderTemplate(w, "personal_links.html", tmplData, http.StatusOK, lang)
+}
+
+// SendHandler emails the members of a period their personal link, in the
+// language of their profile. Members without a known address are skipped.
+func (h *PersonalLinkHandler) SendHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	period := r.FormValue("period")
+	renderError := func(status int, message string) {
+		tmplData := models.PersonalLinksTemplateData{
+			BaseTemplateData: models.BaseTemplateData{Error: message},
+			Period:           period,
+		}
+		h.templateService.RenderTemplate(w, "personal_links.html", tmplData, status, lang)
+	}
+
+	if !h.emailEnabled || !h.emailService.IsConfigured() {
+		renderError(http.StatusServiceUnavailable, "Email service is not properly configured")
+		return
+	}
+
+	links, err := h.issueLinks(period)
+	if err != nil {
+		renderError(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	translator := h.templateService.GetTranslator()
+	sent := 0
+	for _, link := range links {
+		if link.Email == "" {
+			continue
+		}
+
+		memberLang := h.contacts.Language(link.Name, lang)
+		subject := fmt.Sprintf(translator.Translate("email_link_subject", memberLang), link.Month, link.Year)
+		// The body is sent as HTML and the name comes from the export
+		body := fmt.Sprintf(translator.Translate("email_link_body", memberLang),
+			html.EscapeString(link.Name), link.Month, link.Year, html.EscapeString(link.URL), link.ExpiresAt.Format("02.01.2006"))
+		if err := h.emailService.SendEmail(subject, body, []string{link.Email}, nil); err != nil {
+			log.Printf("Error sending personal link to %s: %v", link.Name, err)
+			continue
+		}
+		sent++
+	}
+	log.Printf("Sent %d personal links for %s", sent, period)
+
+	redirect := url.Values{}
+	redirect.Set("period", period)
+	redirect.Set("sent", strconv.Itoa(sent))
+	http.Redirect(w, r, "/coordinator/links?"+redirect.Encode(), http.StatusSeeOther)
+}
+
+// OpenHandler opens the edit page of a personal link, in a session limited
+// to the member and month of the link
+func (h *PersonalLinkHandler) OpenHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	translator := h.templateService.GetTranslator()
+	renderError := func(status int, key string) {
+		tmplData := models.BaseTemplateData{
+			Error: translator.Translate(key, lang),
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, status, lang)
+	}
+
+	link, err := h.linkService.Verify(r.URL.Query().Get("token"), time.Now())
+	if errors.Is(err, services.ErrLinkExpired) {
+		renderError(http.StatusGone, "link_expired")
+		return
+	}
+	if err != nil {
+		renderError(http.StatusForbidden, "link_invalid")
+		return
+	}
+
+	fileData, pc, ok := h.dashboardService.LatestExport()
+	if !ok {
+		renderError(http.StatusNotFound, "link_no_export")
+		return
+	}
+	files := []models.SourceFile{{Data: fileData}}
+	names, _, err := h.excelService.ParseSourcesForNamesAndMonths(files, pc)
+	if err != nil {
+		log.Printf("Error parsing the attendance export for a personal link: %v", err)
+		renderError(http.StatusInternalServerError, "link_no_export")
+		return
+	}
+	if !slices.Contains(names, link.Name) {
+		renderError(http.StatusNotFound, "link_member_missing")
+		return
+	}
+
+	// A newer export may no longer cover the period of an older link
+	rows, _, err := h.excelService.ExtractTableDataWithOptions(files, pc, link.Name, link.Month, services.ExtractOptions{})
+	if err != nil {
+		log.Printf("Error reading the attendance export for a personal link: %v", err)
+		renderError(http.StatusInternalServerError, "link_no_export")
+		return
+	}
+	if len(filterRowsByYear(rows, link.Year)) == 0 {
+		renderError(http.StatusNotFound, "link_period_missing")
+		return
+	}
+
+	// Only the member and period of the link are known to the session
+	month := strconv.Itoa(link.Month)
+	fileToken := h.fileStore.StoreFileDataEntry(models.FileData{
+		Data:       fileData,
+		Kind:       models.SourceKindExcel,
+		Names:      []string{link.Name},
+		Months:     []string{month},
+		SheetNames: pc.Sheets,
+		Scope:      &models.MemberScope{Name: link.Name, Year: link.Year, Month: link.Month},
+	})
+
+	redirect := url.Values{}
+	redirect.Set("fileToken", fileToken)
+	redirect.Set("name", link.Name)
+	redirect.Set("month", month)
+	http.Redirect(w, r, "/edit?"+redirect.Encode(), http.StatusSeeOther)
+}
+
+// issueLinks issues the links of the members with events in a "YYYY-MM"
+// period of the latest export, with the address they would be emailed to
+func (h *PersonalLinkHandler) issueLinks(period string) ([]models.PersonalLink, error) {
+	year, month := parsePeriod(period)
+	if month == 0 {
+		return nil, fmt.Errorf("invalid period %q", period)
+	}
+
+	rows, err := h.dashboardService.BuildLatest(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var links []models.PersonalLink
+	for _, row := range rows {
+		if row.Events == 0 {
+			continue
+		}
+		link := h.linkService.Issue(row.Name, year, month, now)
+		link.Email, _ = h.contacts.Email(row.Name)
+		links = append(links, link)
+	}
+	return links, nil
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+func TestPersonalLinkSessionScope(t *testing.T) {
+	ts := newTestTemplateService(t)
+	fileStore := services.NewFileStore(time.Hour, time.Hour)
+	versionStore := services.NewVersionStore("")
+
+	editHandler := NewEditHandler(nil, nil, fileStore, services.NewDraftStore(time.Hour, time.Hour, ""), ts)
+	processHandler := NewProcessHandler(nil, nil, fileStore, ts, nil, versionStore, services.NewProfileStore(""), false)
+	calendarHandler := NewCalendarHandler(nil, nil, fileStore)
+	diagnosticsHandler := NewDiagnosticsHandler(nil, fileStore, ts)
+	versionHandler := NewVersionHandler(versionStore, services.NewSubmissionStore(""), fileStore, ts)
+
+	fileToken := fileStore.StoreFileDataEntry(models.FileData{
+		Kind:   models.SourceKindExcel,
+		Names:  []string{"Novák Jan"},
+		Months: []string{"3"},
+		Scope:  &models.MemberScope{Name: "Novák Jan", Year: 2024, Month: 3},
+	})
+
+	routes := []struct {
+		path    string
+		method  string
+		handler http.HandlerFunc
+	}{
+		{"/edit", http.MethodPost, editHandler.EditHandler},
+		{"/process", http.MethodPost, processHandler.ProcessHandler},
+		{"/calendar.ics", http.MethodGet, calendarHandler.ExportHandler},
+		{"/versions", http.MethodGet, versionHandler.VersionsHandler},
+	}
+	periods := []struct {
+		name   string
+		member string
+		month  string
+	}{
+		{"other member", "Dvořák Petr", "3"},
+		{"other month", "Novák Jan", "4"},
+	}
+
+	for _, route := range routes {
+		for _, period := range periods {
+			t.Run(route.path+" "+period.name, func(t *testing.T) {
+				form := url.Values{}
+				form.Set("fileToken", fileToken)
+				form.Set("name", period.member)
+				form.Set("year", "2024")
+				form.Set("month", period.month)
+
+				var req *http.Request
+				if route.method == http.MethodPost {
+					req = httptest.NewRequest(http.MethodPost, route.path, strings.NewReader(form.Encode()))
+					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				} else {
+					req = httptest.NewRequest(http.MethodGet, route.path+"?"+form.Encode(), nil)
+				}
+
+				rec := serveTest(route.handler, req)
+				if rec.Code != http.StatusForbidden {
+					t.Errorf("Expected status %d, got %d", http.StatusForbidden, rec.Code)
+				}
+			})
+		}
+	}
+
+	// Upload details list every member of the export, so a personal link
+	// session cannot open them at all
+	for _, format := range []string{"", "json"} {
+		t.Run("/upload/diagnostics "+format, func(t *testing.T) {
+			query := url.Values{}
+			query.Set("fileToken", fileToken)
+			query.Set("format", format)
+
+			rec := serveTest(diagnosticsHandler.DiagnosticsHandler, httptest.NewRequest(http.MethodGet, "/upload/diagnostics?"+query.Encode(), nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+			if strings.Contains(rec.Body.String(), "Novák Jan") {
+				t.Errorf("Expected no member names in the response")
+			}
+		})
+	}
+}
+
+func TestPersonalLinkOpenRejected(t *testing.T) {
+	now := time.Now()
+	linkService := services.NewPersonalLinkService([]byte("secret"), 24*time.Hour, "https://example.com")
+	handler := NewPersonalLinkHandler(nil, nil, services.NewFileStore(time.Hour, time.Hour), linkService, nil, nil, newTestTemplateService(t), false)
+
+	tokenOf := func(link models.PersonalLink) string {
+		parsed, err := url.Parse(link.URL)
+		if err != nil {
+			t.Fatalf("failed to parse link: %v", err)
+		}
+		return parsed.Query().Get("token")
+	}
+
+	valid := tokenOf(linkService.Issue("Novák Jan", 2024, 3, now))
+	_, signature, _ := strings.Cut(valid, ".")
+	tampered, _, _ := strings.Cut(tokenOf(linkService.Issue("Dvořák Petr", 2024, 3, now)), ".")
+	other := services.NewPersonalLinkService([]byte("other secret"), 24*time.Hour, "https://example.com")
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"missing token", "", http.StatusForbidden},
+		{"malformed token", "not-a-token", http.StatusForbidden},
+		{"signed with another secret", tokenOf(other.Issue("Novák Jan", 2024, 3, now)), http.StatusForbidden},
+		{"tampered payload", tampered + "." + signature, http.StatusForbidden},
+		{"expired", tokenOf(linkService.Issue("Novák Jan", 2024, 3, now.Add(-48*time.Hour))), http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			query.Set("token", tt.token)
+
+			rec := serveTest(handler.OpenHandler, httptest.NewRequest(http.MethodGet, "/me?"+query.Encode(), nil))
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+			if location := rec.Header().Get("Location"); location != "" {
+				t.Errorf("Expected no redirect, got %q", location)
+			}
+		})
+	}
+}
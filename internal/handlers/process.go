@@ -6,31 +6,45 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/i18n"
 	"timesheet-filler/internal/models"
 	"timesheet-filler/internal/services"
 	"timesheet-filler/internal/utils"
 )
 
 type ProcessHandler struct {
-	excelService    *services.ExcelService
-	fileStore       *services.FileStore
-	templateService *services.TemplateService
-	emailEnabled    bool
+	excelService      *services.ExcelService
+	pdfService        *services.PDFService
+	fileStore         *services.FileStore
+	templateService   *services.TemplateService
+	validationService *services.ValidationService
+	versionStore      *services.VersionStore
+	profileStore      *services.ProfileStore
+	emailEnabled      bool
 }
 
 func NewProcessHandler(
 	excelService *services.ExcelService,
+	pdfService *services.PDFService,
 	fileStore *services.FileStore,
 	templateService *services.TemplateService,
+	validationService *services.ValidationService,
+	versionStore *services.VersionStore,
+	profileStore *services.ProfileStore,
 	emailEnabled bool,
 ) *ProcessHandler {
 	return &ProcessHandler{
-		excelService:    excelService,
-		fileStore:       fileStore,
-		templateService: templateService,
-		emailEnabled:    emailEnabled,
+		excelService:      excelService,
+		pdfService:        pdfService,
+		fileStore:         fileStore,
+		templateService:   templateService,
+		validationService: validationService,
+		versionStore:      versionStore,
+		profileStore:      profileStore,
+		emailEnabled:      emailEnabled,
 	}
 }
 
@@ -78,19 +92,29 @@ func (h *ProcessHandler) ProcessHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Retrieve table data from form
-	dates := r.Form["date[]"]
-	startTimes := r.Form["start_time[]"]
-	endTimes := r.Form["end_time[]"]
-	notes := r.Form["note[]"]
+	// Sessions opened from a personal link only generate the report of their
+	// member and month. Expired sessions are still accepted, since a draft can
+	// be edited after the upload expired.
+	if fileData, ok := h.fileStore.GetFileData(fileToken); ok && !sessionAllows(fileData, name, month) {
+		tmplData := models.BaseTemplateData{
+			Error: "This link only opens the timesheet it was sent for.",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusForbidden, lang)
+		return
+	}
+
+	// The year the edit page was opened for, which its drafts are kept under
+	editYear, _ := strconv.Atoi(r.FormValue("year"))
 
-	if len(dates) == 0 {
+	// Retrieve table data from form
+	if len(r.Form["date[]"]) == 0 {
 		tmplData := models.EditTemplateData{
 			BaseTemplateData: models.BaseTemplateData{
 				Error: "Please enter at least one row of data.",
 			},
 			FileToken: fileToken,
 			Name:      name,
+			Year:      editYear,
 			Month:     monthStr,
 		}
 		h.templateService.RenderTemplate(w, "edit.html", tmplData, http.StatusOK, lang)
@@ -98,14 +122,37 @@ func (h *ProcessHandler) ProcessHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Prepare data for processing
-	var tableData []models.TableRow
-	for i := range dates {
-		tableData = append(tableData, models.TableRow{
-			Date:      dates[i],
-			StartTime: startTimes[i],
-			EndTime:   endTimes[i],
-			Note:      notes[i],
-		})
+	tableData := tableRowsFromForm(r)
+
+	// Validate the rows and send the user back to the edit page if there are
+	// blocking problems, unless they explicitly chose to ignore them
+	tableData = h.validationService.Normalize(tableData)
+	issues := h.validationService.Validate(tableData, month)
+	override := r.FormValue("override") == "true"
+	if services.HasBlockingIssues(issues) && !override {
+		if editYear == 0 {
+			editYear = services.ReportYear(tableData)
+		}
+		tmplData := models.EditTemplateData{
+			BaseTemplateData: models.BaseTemplateData{
+				Error: h.templateService.GetTranslator().Translate("validation_failed", lang),
+			},
+			FileToken:   fileToken,
+			Name:        name,
+			Year:        editYear,
+			Month:       monthStr,
+			TableData:   tableData,
+			Issues:      issues,
+			RowIssues:   services.GroupIssuesByRow(issues),
+			CanOverride: true,
+			MaxRows:     h.excelService.ReportMappingFor(tableData).MaxRows,
+			Holidays:    holidaysOfRows(h.excelService.Holidays(), tableData),
+		}
+		h.templateService.RenderTemplate(w, "edit.html", tmplData, http.StatusUnprocessableEntity, lang)
+		return
+	}
+	if len(issues) > 0 {
+		log.Printf("Generating report for %s with %d validation issues (override: %t)", name, len(issues), override)
 	}
 
 	// Process the Excel file
@@ -117,10 +164,11 @@ func (h *ProcessHandler) ProcessHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Generate filename
-	firstname, lastname := utils.SplitName(name)
+	firstname, lastname := h.excelService.SplitName(name)
 	cleanFirstname := utils.RemoveDiacritics(firstname)
 	cleanLastname := utils.RemoveDiacritics(lastname)
-	filename := fmt.Sprintf("Gorily_vykaz-prace_%02d2024_%s_%s.xlsx", month, cleanFirstname, cleanLastname)
+	year := services.ReportYear(tableData)
+	filename := fmt.Sprintf("Gorily_vykaz-prace_%02d%d_%s_%s.xlsx", month, year, cleanFirstname, cleanLastname)
 	filename = utils.SanitizeFilename(filename)
 
 	// Write the Excel file to a buffer
@@ -134,19 +182,66 @@ func (h *ProcessHandler) ProcessHandler(w http.ResponseWriter, r *http.Request)
 	// Store the file with a new token for download
 	downloadToken := h.fileStore.StoreTempFile(buf.Bytes(), filename)
 
+	// Render the same entries as a non-editable PDF
+	pdfData, err := h.pdfService.RenderTimesheet(services.PDFReport{
+		Name:      name,
+		FirstName: firstname,
+		LastName:  lastname,
+		Period:    fmt.Sprintf("%02d/%d", month, year),
+		TableData: tableData,
+		Labels:    pdfLabels(h.templateService.GetTranslator(), lang),
+	})
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		log.Printf("Error rendering PDF report: %v", err)
+		return
+	}
+	pdfFilename := strings.TrimSuffix(filename, ".xlsx") + ".pdf"
+	pdfDownloadToken := h.fileStore.StoreTempFile(pdfData, pdfFilename)
+
+	// Keep every generated report as a new version of the member's period
+	version := h.versionStore.Add(name, year, month, tableData, filename)
+
+	// Prefill the member's address in the email and submission forms
+	profile, _ := h.profileStore.Get(name)
+
 	// Render the download template
 	tmplData := models.DownloadTemplateData{
 		BaseTemplateData: models.BaseTemplateData{},
 		DownloadToken:    downloadToken,
 		FileName:         filename,
+		PDFDownloadToken: pdfDownloadToken,
+		PDFFileName:      pdfFilename,
 		FileToken:        fileToken,
 		Name:             name,
 		Month:            monthStr,
 		EmailEnabled:     h.emailEnabled,
 		EmailOptions: models.EmailOptions{
 			SendToSelf: false,
-			UserEmail:  "",
+			UserEmail:  profile.Email,
 		},
+		Totals:        services.ComputeTotals(tableData),
+		SheetCount:    len(h.excelService.ReportSheetNames(tableData)),
+		VersionID:     version.ID,
+		VersionNumber: version.Number,
+		Year:          year,
 	}
 	h.templateService.RenderTemplate(w, "download.html", tmplData, http.StatusOK, lang)
 }
+
+// pdfLabels returns the PDF timesheet texts in the given language
+func pdfLabels(translator *i18n.Translator, lang string) services.PDFLabels {
+	return services.PDFLabels{
+		Title:     translator.Translate("pdf_title", lang),
+		Name:      translator.Translate("pdf_name", lang),
+		Period:    translator.Translate("pdf_period", lang),
+		Date:      translator.Translate("date", lang),
+		StartTime: translator.Translate("start_time", lang),
+		EndTime:   translator.Translate("end_time", lang),
+		Hours:     translator.Translate("hours", lang),
+		Note:      translator.Translate("note", lang),
+		Total:     translator.Translate("total_hours", lang),
+		Signature: translator.Translate("pdf_signature", lang),
+		Page:      translator.Translate("pdf_page", lang),
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+// Languages a member can prefer for the emails they receive
+var profileLanguages = map[string]bool{"": true, "en": true, "cs": true}
+
+type ProfileHandler struct {
+	profileStore    *services.ProfileStore
+	templateService *services.TemplateService
+}
+
+func NewProfileHandler(
+	profileStore *services.ProfileStore,
+	templateService *services.TemplateService,
+) *ProfileHandler {
+	return &ProfileHandler{
+		profileStore:    profileStore,
+		templateService: templateService,
+	}
+}
+
+// ListHandler shows the member profiles and a form for adding one, or for
+// editing the profile of the member given in the query
+func (h *ProfileHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	tmplData := models.ProfilesTemplateData{
+		Profiles: h.profileStore.List(),
+	}
+	if member := r.URL.Query().Get("member"); member != "" {
+		profile, ok := h.profileStore.Get(member)
+		if !ok {
+			profile = models.MemberProfile{Member: member}
+		}
+		tmplData.Profile = profile
+		tmplData.Editing = ok
+	}
+	h.templateService.RenderTemplate(w, "profiles.html", tmplData, http.StatusOK, lang)
+}
+
+// SaveHandler creates or updates a member profile
+func (h *ProfileHandler) SaveHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profile := models.MemberProfile{
+		Member:     r.FormValue("member"),
+		FirstName:  r.FormValue("firstName"),
+		LastName:   r.FormValue("lastName"),
+		Email:      strings.TrimSpace(r.FormValue("email")),
+		Language:   r.FormValue("language"),
+		Team:       r.FormValue("team"),
+		Recipients: splitRecipients(r.FormValue("recipients")),
+	}
+
+	renderError := func(message string) {
+		tmplData := models.ProfilesTemplateData{
+			BaseTemplateData: models.BaseTemplateData{Error: message},
+			Profiles:         h.profileStore.List(),
+			Profile:          profile,
+		}
+		_, tmplData.Editing = h.profileStore.Get(profile.Member)
+		h.templateService.RenderTemplate(w, "profiles.html", tmplData, http.StatusBadRequest, lang)
+	}
+
+	translator := h.templateService.GetTranslator()
+	if profile.Email != "" && !isValidEmail(profile.Email) {
+		renderError(translator.Translate("profile_invalid_email", lang))
+		return
+	}
+	for _, recipient := range profile.Recipients {
+		if !isValidEmail(recipient) {
+			renderError(translator.Translate("profile_invalid_email", lang))
+			return
+		}
+	}
+	if !profileLanguages[profile.Language] {
+		renderError("Bad Request: Unknown language " + profile.Language)
+		return
+	}
+
+	saved, err := h.profileStore.Save(profile)
+	if err != nil {
+		renderError(translator.Translate("profile_member_required", lang))
+		return
+	}
+	log.Printf("Profile of %s saved", saved.Member)
+
+	http.Redirect(w, r, "/coordinator/profiles", http.StatusSeeOther)
+}
+
+// DeleteHandler removes a member profile
+func (h *ProfileHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	member := r.FormValue("member")
+	if !h.profileStore.Delete(member) {
+		http.Error(w, "Profile Not Found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Profile of %s deleted", member)
+
+	http.Redirect(w, r, "/coordinator/profiles", http.StatusSeeOther)
+}
+
+// splitRecipients reads a comma or newline separated list of addresses
+func splitRecipients(value string) []string {
+	var recipients []string
+	for _, recipient := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r'
+	}) {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	return recipients
+}
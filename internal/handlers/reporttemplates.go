@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+type ReportTemplateHandler struct {
+	excelService    *services.ExcelService
+	reportTemplates *services.ReportTemplateStore
+	templateService *services.TemplateService
+	maxUploadSize   int64
+}
+
+func NewReportTemplateHandler(
+	excelService *services.ExcelService,
+	reportTemplates *services.ReportTemplateStore,
+	templateService *services.TemplateService,
+	maxUploadSize int64,
+) *ReportTemplateHandler {
+	return &ReportTemplateHandler{
+		excelService:    excelService,
+		reportTemplates: reportTemplates,
+		templateService: templateService,
+		maxUploadSize:   maxUploadSize,
+	}
+}
+
+// ListHandler shows the uploaded report templates and the upload form,
+// prefilled with the mapping of the configured template
+func (h *ReportTemplateHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	tmplData := models.ReportTemplatesTemplateData{
+		Templates: h.reportTemplates.List(),
+		Mapping:   h.excelService.GetReportMapping(),
+	}
+	h.templateService.RenderTemplate(w, "report_templates.html", tmplData, http.StatusOK, lang)
+}
+
+// UploadHandler validates an uploaded report template against its mapping
+// and stores it, inactive until activated
+func (h *ReportTemplateHandler) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	renderError := func(status int, message string, mapping models.ReportMapping, issues []models.ValidationIssue) {
+		tmplData := models.ReportTemplatesTemplateData{
+			BaseTemplateData: models.BaseTemplateData{Error: message},
+			Templates:        h.reportTemplates.List(),
+			Mapping:          mapping,
+			Issues:           issues,
+		}
+		h.templateService.RenderTemplate(w, "report_templates.html", tmplData, status, lang)
+	}
+
+	if err := r.ParseMultipartForm(h.maxUploadSize); err != nil {
+		log.Printf("Error parsing form data: %v", err)
+		renderError(http.StatusBadRequest, "Bad Request: Unable to parse form data.", h.excelService.GetReportMapping(), nil)
+		return
+	}
+
+	mapping := mappingFromForm(r)
+
+	fileHeaders := r.MultipartForm.File["templateFile"]
+	if len(fileHeaders) == 0 {
+		renderError(http.StatusBadRequest, "Bad Request: Unable to retrieve file.", mapping, nil)
+		return
+	}
+	header := fileHeaders[0]
+	data, err := readUploadedFile(header)
+	if err != nil {
+		log.Printf("Error reading report template: %v", err)
+		renderError(http.StatusInternalServerError, "Internal Server Error: Unable to read file.", mapping, nil)
+		return
+	}
+
+	if issues := services.ValidateReportTemplate(data, mapping); len(issues) > 0 {
+		message := h.templateService.GetTranslator().Translate("report_templates_invalid", lang)
+		renderError(http.StatusUnprocessableEntity, message, mapping, issues)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		name = header.Filename
+	}
+	tmpl, err := h.reportTemplates.Add(models.ReportTemplate{
+		Name:     name,
+		Filename: header.Filename,
+		Mapping:  mapping,
+	}, data)
+	if err != nil {
+		log.Printf("Error storing report template: %v", err)
+		renderError(http.StatusInternalServerError, "Internal Server Error: Unable to store the template.", mapping, nil)
+		return
+	}
+	log.Printf("Report template %q uploaded as %s", tmpl.Name, tmpl.ID)
+
+	http.Redirect(w, r, "/admin/templates", http.StatusSeeOther)
+}
+
+// PreviewHandler downloads a report generated from an uploaded template with
+// sample entries
+func (h *ReportTemplateHandler) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, data, ok := h.reportTemplates.Get(r.URL.Query().Get("id"))
+	if !ok {
+		http.Error(w, "Report Template Not Found", http.StatusNotFound)
+		return
+	}
+
+	// Sample entries fall into the first month the template is valid for
+	period := tmpl.ValidFrom
+	if period.IsZero() {
+		period = time.Now()
+	}
+	var sample []models.TableRow
+	for day, note := range []string{"Practice", "Game", "Practice"} {
+		date := time.Date(period.Year(), period.Month(), 2+day*7, 0, 0, 0, 0, time.UTC)
+		sample = append(sample, models.TableRow{
+			Date:      date.Format("2006-01-02"),
+			StartTime: "18:00",
+			EndTime:   "20:00",
+			Note:      note,
+		})
+	}
+
+	f, err := h.excelService.GenerateSample(data, tmpl.Mapping, "Novák Jan", sample)
+	if err != nil {
+		log.Printf("Error generating report template preview: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	buf := new(bytes.Buffer)
+	if err := f.Write(buf); err != nil {
+		log.Printf("Error writing report template preview: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "preview_" + tmpl.Filename
+	w.Header().Set("Content-Type", contentTypeForFile(filename))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("Error sending report template preview: %v", err)
+	}
+}
+
+// ActivateHandler activates a template for a validity range of report
+// periods, or deactivates it
+func (h *ReportTemplateHandler) ActivateHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	renderError := func(status int, message string) {
+		tmplData := models.ReportTemplatesTemplateData{
+			BaseTemplateData: models.BaseTemplateData{Error: message},
+			Templates:        h.reportTemplates.List(),
+			Mapping:          h.excelService.GetReportMapping(),
+		}
+		h.templateService.RenderTemplate(w, "report_templates.html", tmplData, status, lang)
+	}
+
+	id := r.FormValue("id")
+	if r.FormValue("action") == "deactivate" {
+		if err := h.reportTemplates.Deactivate(id); err != nil {
+			renderError(http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Report template %s deactivated", id)
+		http.Redirect(w, r, "/admin/templates", http.StatusSeeOther)
+		return
+	}
+
+	// Templates apply to whole report months. An empty month leaves the range
+	// open on that side; dates, as sent by browsers without month inputs,
+	// stand for their month.
+	var validFrom, validTo time.Time
+	for _, field := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"validFrom", &validFrom},
+		{"validTo", &validTo},
+	} {
+		value := r.FormValue(field.name)
+		if value == "" {
+			continue
+		}
+		month, err := time.Parse("2006-01", value)
+		if err != nil {
+			date, dateErr := time.Parse("2006-01-02", value)
+			if dateErr != nil {
+				renderError(http.StatusBadRequest, "Bad Request: Invalid month "+value)
+				return
+			}
+			month = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		*field.value = month
+	}
+
+	tmpl, err := h.reportTemplates.Activate(id, validFrom, validTo)
+	if err != nil {
+		renderError(http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("Report template %q activated", tmpl.Name)
+
+	http.Redirect(w, r, "/admin/templates", http.StatusSeeOther)
+}
+
+// mappingFromForm reads the field mapping of an uploaded template. The form
+// must already be parsed.
+func mappingFromForm(r *http.Request) models.ReportMapping {
+	reference := func(field string) string {
+		return strings.ToUpper(strings.TrimSpace(r.FormValue(field)))
+	}
+	number := func(field string) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(r.FormValue(field)))
+		return n
+	}
+
+	return models.ReportMapping{
+		TargetSheet:         strings.TrimSpace(r.FormValue("targetSheet")),
+		FirstNameCell:       reference("firstNameCell"),
+		LastNameCell:        reference("lastNameCell"),
+		StartRow:            number("startRow"),
+		MaxRows:             number("maxRows"),
+		DateColumn:          reference("dateColumn"),
+		StartTimeColumn:     reference("startTimeColumn"),
+		EndTimeColumn:       reference("endTimeColumn"),
+		NoteColumn:          reference("noteColumn"),
+		HoursColumn:         reference("hoursColumn"),
+		TotalHoursCell:      reference("totalHoursCell"),
+		TimeStyleID:         number("timeStyleID"),
+		HolidayColumn:       reference("holidayColumn"),
+		DayOffFillColor:     reference("dayOffFillColor"),
+		ApprovedByCell:      reference("approvedByCell"),
+		ApprovedAtCell:      reference("approvedAtCell"),
+		ApprovedByLabelCell: reference("approvedByLabelCell"),
+		ApprovedAtLabelCell: reference("approvedAtLabelCell"),
+	}
+}
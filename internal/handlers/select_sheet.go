@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+
 	"timesheet-filler/internal/contextkeys"
 	"timesheet-filler/internal/models"
 	"timesheet-filler/internal/services"
@@ -42,13 +44,26 @@ func (h *SelectSheethandler) SelectSheetHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// Several sheets of the same structure can be picked, or all sheets
+	// matching a pattern
 	fileToken := r.FormValue("fileToken")
-	selectedSheet := r.FormValue("sheetName")
+	selectedSheets := r.Form["sheetName"]
+	if pattern := strings.TrimSpace(r.FormValue("sheetPattern")); pattern != "" {
+		selectedSheets = append(selectedSheets, pattern)
+	}
 
-	log.Printf("User selected sheet: '%s'", selectedSheet)
+	log.Printf("User selected sheets: '%s'", strings.Join(selectedSheets, "', '"))
+
+	if len(selectedSheets) == 0 {
+		tmplData := models.BaseTemplateData{
+			Error: "Bad Request: No sheet selected.",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
 
 	fileData, ok := h.fileStore.GetFileData(fileToken)
-	if !ok {
+	if !ok || fileData.Scope != nil {
 		tmplData := models.BaseTemplateData{
 			Error: "Invalid session. Please re-upload your file.",
 		}
@@ -56,9 +71,10 @@ func (h *SelectSheethandler) SelectSheetHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	h.excelService.SetSourceSheet(selectedSheet)
-
-	names, monthsInt, err := h.excelService.ParseExcelForNamesAndMonths(fileData.Data)
+	// The chosen sheets only apply to this session
+	files := sourceFiles(fileData)
+	pc := services.ParseContext{Sheets: selectedSheets}
+	names, monthsInt, err := h.excelService.ParseSourcesForNamesAndMonths(files, pc)
 	if err != nil {
 		log.Printf("Error parsing Excel after sheet selection: %v", err)
 
@@ -80,13 +96,36 @@ func (h *SelectSheethandler) SelectSheetHandler(w http.ResponseWriter, r *http.R
 		defaultMonth = strconv.Itoa(maxMonth)
 	}
 
-	fileToken = h.fileStore.StoreFileData(fileData.Data, names, months, selectedSheet)
+	eventTypes, err := h.excelService.EventTypes(files, pc)
+	if err != nil {
+		log.Printf("Error reading event types: %v", err)
+	}
+
+	// Report date cells that cannot be read instead of silently dropping them
+	parseFailures, err := h.excelService.DateParseFailures(files, pc)
+	if err != nil {
+		log.Printf("Error checking dates: %v", err)
+	}
+
+	fileToken = h.fileStore.StoreFileDataEntry(models.FileData{
+		Data:       fileData.Data,
+		Files:      fileData.Files,
+		Kind:       models.SourceKindExcel,
+		Names:      names,
+		Months:     months,
+		EventTypes: eventTypes,
+		SheetNames: selectedSheets,
+	})
 
 	tmplData := models.SelectTemplateData{
-		FileToken:    fileToken,
-		Names:        names,
-		Months:       months,
-		DefaultMonth: defaultMonth,
+		FileToken:     fileToken,
+		Names:         names,
+		Months:        months,
+		EventTypes:    eventTypes,
+		DefaultMonth:  defaultMonth,
+		ParseFailures: parseFailures,
+		Files:         sourceFileNames(files),
+		Attendance:    true,
 	}
 
 	h.templateService.RenderTemplate(w, "select.html", tmplData, http.StatusOK, lang)
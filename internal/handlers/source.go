@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"timesheet-filler/internal/holidays"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+	"timesheet-filler/internal/utils"
+)
+
+// extractTableData reads the rows of a member and month from an uploaded
+// source, whichever kind of file it was. The options only apply to attendance
+// exports, the counts of left out events to exports and calendars.
+func extractTableData(
+	excelService *services.ExcelService,
+	icalService *services.ICalService,
+	fileData models.FileData,
+	name string,
+	month int,
+	opts services.ExtractOptions,
+) ([]models.TableRow, map[string]int, error) {
+	switch fileData.Kind {
+	case models.SourceKindCalendar:
+		rows, skipped, err := icalService.Parse(fileData.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return filterRowsByMonth(rows, month), skipped, nil
+	case models.SourceKindReport:
+		_, rows, err := excelService.ExtractReportData(fileData.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return filterRowsByMonth(rows, month), nil, nil
+	case models.SourceKindExcel, "":
+		rows, excluded, err := excelService.ExtractTableDataWithOptions(sourceFiles(fileData), parseContext(fileData), name, month, opts)
+		// The export may hold the month of several years, a personal link
+		// only opens the year it was sent for
+		if err == nil && fileData.Scope != nil {
+			rows = filterRowsByYear(rows, fileData.Scope.Year)
+		}
+		return rows, excluded, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported source kind %q", fileData.Kind)
+	}
+}
+
+// sourceFiles returns the attendance exports of a session. Sessions stored
+// with a single file only have Data set.
+func sourceFiles(fileData models.FileData) []models.SourceFile {
+	if len(fileData.Files) > 0 {
+		return fileData.Files
+	}
+	return []models.SourceFile{{Data: fileData.Data}}
+}
+
+// sessionAllows reports whether a session may open the timesheet of a member
+// for a month. Sessions opened from a personal link only open the member and
+// month of the link.
+func sessionAllows(fileData models.FileData, name string, month int) bool {
+	scope := fileData.Scope
+	return scope == nil || (scope.Name == name && scope.Month == month)
+}
+
+// sessionShowsPeriod reports whether a session may show the report history of
+// a member for a period: a personal link session only that of its member,
+// year and month, any other session only that of members in its files
+func sessionShowsPeriod(fileData models.FileData, name string, year, month int) bool {
+	if fileData.Scope != nil {
+		return sessionAllows(fileData, name, month) && fileData.Scope.Year == year
+	}
+	return slices.Contains(fileData.Names, name)
+}
+
+// parseContext returns how the attendance exports of a session are read,
+// i.e. from the sheets chosen for it
+func parseContext(fileData models.FileData) services.ParseContext {
+	return services.ParseContext{Sheets: fileData.SheetNames}
+}
+
+// extractOptionsFromForm reads the event selection made on the select page.
+// The form must already be parsed.
+func extractOptionsFromForm(r *http.Request) services.ExtractOptions {
+	return services.ExtractOptions{
+		EventTypes:       r.Form["eventType"],
+		IncludeTentative: r.FormValue("includeTentative") == "true",
+	}
+}
+
+// filterRowsByYear keeps the rows whose date falls into year
+func filterRowsByYear(rows []models.TableRow, year int) []models.TableRow {
+	var filtered []models.TableRow
+	for _, row := range rows {
+		if date, err := time.Parse("2006-01-02", row.Date); err == nil && date.Year() == year {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+// filterRowsByMonth keeps the rows whose date falls into month
+func filterRowsByMonth(rows []models.TableRow, month int) []models.TableRow {
+	var filtered []models.TableRow
+	for _, row := range rows {
+		if date, err := time.Parse("2006-01-02", row.Date); err == nil && int(date.Month()) == month {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+// tableRowsFromForm reads the rows submitted from the edit page. The form
+// must already be parsed.
+func tableRowsFromForm(r *http.Request) []models.TableRow {
+	dates := r.Form["date[]"]
+	startTimes := r.Form["start_time[]"]
+	endTimes := r.Form["end_time[]"]
+	notes := r.Form["note[]"]
+	eventTypes := r.Form["event_type[]"]
+	tentative := r.Form["tentative[]"]
+	origins := r.Form["origin[]"]
+
+	var tableData []models.TableRow
+	for i := range dates {
+		tableData = append(tableData, models.TableRow{
+			Date:      dates[i],
+			StartTime: utils.SafeGetCellValue(startTimes, i),
+			EndTime:   utils.SafeGetCellValue(endTimes, i),
+			Note:      utils.SafeGetCellValue(notes, i),
+			EventType: utils.SafeGetCellValue(eventTypes, i),
+			Tentative: utils.SafeGetCellValue(tentative, i) == "true",
+			Origin:    utils.SafeGetCellValue(origins, i),
+		})
+	}
+	return tableData
+}
+
+// monthsOfRows returns the months that rows fall into, sorted, together with
+// the latest of them as the default selection
+func monthsOfRows(rows []models.TableRow) ([]string, string) {
+	monthSet := make(map[int]bool)
+	for _, row := range rows {
+		if date, err := time.Parse("2006-01-02", row.Date); err == nil {
+			monthSet[int(date.Month())] = true
+		}
+	}
+
+	var monthsInt []int
+	for m := range monthSet {
+		monthsInt = append(monthsInt, m)
+	}
+	sort.Ints(monthsInt)
+
+	var months []string
+	for _, m := range monthsInt {
+		months = append(months, strconv.Itoa(m))
+	}
+
+	var defaultMonth string
+	if len(monthsInt) > 0 {
+		defaultMonth = strconv.Itoa(monthsInt[len(monthsInt)-1])
+	}
+
+	return months, defaultMonth
+}
+
+// holidaysOfRows returns the holidays of every year the rows fall into, or of
+// the current year when there are none, for flagging rows on the edit page
+func holidaysOfRows(calendar *holidays.Calendar, rows []models.TableRow) map[string]string {
+	years := make(map[int]bool)
+	for _, row := range rows {
+		if date, err := time.Parse("2006-01-02", row.Date); err == nil {
+			years[date.Year()] = true
+		}
+	}
+	if len(years) == 0 {
+		years[time.Now().Year()] = true
+	}
+
+	days := make(map[string]string)
+	for year := range years {
+		for date, name := range calendar.Year(year) {
+			days[date] = name
+		}
+	}
+	return days
+}
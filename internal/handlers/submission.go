@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+type SubmissionHandler struct {
+	excelService    *services.ExcelService
+	versionStore    *services.VersionStore
+	submissionStore *services.SubmissionStore
+	profileStore    *services.ProfileStore
+	emailService    *services.EmailService
+	templateService *services.TemplateService
+	emailEnabled    bool
+}
+
+func NewSubmissionHandler(
+	excelService *services.ExcelService,
+	versionStore *services.VersionStore,
+	submissionStore *services.SubmissionStore,
+	profileStore *services.ProfileStore,
+	emailService *services.EmailService,
+	templateService *services.TemplateService,
+	emailEnabled bool,
+) *SubmissionHandler {
+	return &SubmissionHandler{
+		excelService:    excelService,
+		versionStore:    versionStore,
+		submissionStore: submissionStore,
+		profileStore:    profileStore,
+		emailService:    emailService,
+		templateService: templateService,
+		emailEnabled:    emailEnabled,
+	}
+}
+
+// SubmitHandler hands in a generated report version for approval
+func (h *SubmissionHandler) SubmitHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, ok := h.versionStore.Get(r.FormValue("versionID"))
+	if !ok {
+		tmplData := models.BaseTemplateData{
+			Error: "Report version not found. Please generate the report again.",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusNotFound, lang)
+		return
+	}
+
+	memberEmail := r.FormValue("memberEmail")
+	if memberEmail != "" && !isValidEmail(memberEmail) {
+		tmplData := models.BaseTemplateData{
+			Error: "Please enter a valid email address",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+
+	submission := h.submissionStore.Submit(version, memberEmail)
+	log.Printf("Report version %d of %s for %02d/%d submitted for approval", version.Number, version.Name, version.Month, version.Year)
+
+	http.Redirect(w, r, "/submission?id="+url.QueryEscape(submission.ID), http.StatusSeeOther)
+}
+
+// StatusHandler shows a member the state of their submission
+func (h *SubmissionHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	submission, ok := h.submissionStore.Get(r.URL.Query().Get("id"))
+	if !ok {
+		tmplData := models.BaseTemplateData{
+			Error: "Submission not found.",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusNotFound, lang)
+		return
+	}
+
+	version, _ := h.versionStore.Get(submission.VersionID)
+	tmplData := models.SubmissionTemplateData{
+		Submission: submission,
+		Version:    version,
+	}
+	h.templateService.RenderTemplate(w, "submission.html", tmplData, http.StatusOK, lang)
+}
+
+// QueueHandler lists submissions for coordinators, pending ones by default
+func (h *SubmissionHandler) QueueHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	query := r.URL.Query()
+	status := query.Get("status")
+	if !query.Has("status") {
+		status = models.SubmissionPending
+	}
+
+	period := query.Get("period")
+	year, month := parsePeriod(period)
+
+	submissions := h.submissionStore.List(status, year, month)
+	versions := make(map[string]models.ReportVersion)
+	for _, s := range submissions {
+		if v, ok := h.versionStore.Get(s.VersionID); ok {
+			versions[s.VersionID] = v
+		}
+	}
+
+	reviewer, _, _ := r.BasicAuth()
+	tmplData := models.SubmissionsTemplateData{
+		Status:      status,
+		Period:      period,
+		Submissions: submissions,
+		Versions:    versions,
+		Reviewer:    reviewer,
+	}
+	h.templateService.RenderTemplate(w, "submissions.html", tmplData, http.StatusOK, lang)
+}
+
+// ReviewHandler approves or rejects a submission. Members whose submission
+// was rejected are notified by email when they left an address.
+func (h *SubmissionHandler) ReviewHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The reviewer is always the signed in coordinator, so nobody can
+	// approve in another's name
+	reviewer, _, _ := r.BasicAuth()
+
+	approve := r.FormValue("decision") == "approve"
+	submission, err := h.submissionStore.Review(r.FormValue("id"), approve, reviewer, r.FormValue("comment"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("Submission of %s for %02d/%d %s by %s", submission.Name, submission.Month, submission.Year, submission.Status, reviewer)
+
+	if submission.Status == models.SubmissionRejected {
+		h.notifyRejection(submission, lang)
+	}
+
+	redirect := url.Values{}
+	redirect.Set("status", r.FormValue("status"))
+	redirect.Set("period", r.FormValue("period"))
+	http.Redirect(w, r, "/coordinator/submissions?"+redirect.Encode(), http.StatusSeeOther)
+}
+
+// DownloadHandler generates the report of a submission, stamped with the
+// approval when it was approved
+func (h *SubmissionHandler) DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	submission, ok := h.submissionStore.Get(r.URL.Query().Get("id"))
+	if !ok {
+		http.Error(w, "Submission Not Found", http.StatusNotFound)
+		return
+	}
+
+	version, ok := h.versionStore.Get(submission.VersionID)
+	if !ok {
+		http.Error(w, "Report Version Not Found", http.StatusNotFound)
+		return
+	}
+
+	f, err := h.excelService.ProcessExcelFile(version.Name, version.TableData)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		log.Printf("Error generating submitted report: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if submission.Status == models.SubmissionApproved {
+		translator := h.templateService.GetTranslator()
+		labels := services.ApprovalLabels{
+			ApprovedBy: translator.Translate("report_approved_by", lang),
+			ApprovedAt: translator.Translate("report_approved_at", lang),
+		}
+		if err := h.excelService.StampApproval(f, version.TableData, submission.Reviewer, submission.ReviewedAt, labels); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			log.Printf("Error stamping approval: %v", err)
+			return
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := f.Write(buf); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		log.Printf("Error writing submitted report: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeForFile(version.FileName))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", version.FileName))
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("Error sending submitted report: %v", err)
+	}
+}
+
+func (h *SubmissionHandler) notifyRejection(submission models.Submission, lang string) {
+	if submission.MemberEmail == "" || !h.emailEnabled || !h.emailService.IsConfigured() {
+		return
+	}
+
+	// Members read the email in their preferred language
+	if profile, ok := h.profileStore.Get(submission.Name); ok && profile.Language != "" {
+		lang = profile.Language
+	}
+
+	translator := h.templateService.GetTranslator()
+	period := fmt.Sprintf("%02d/%d", submission.Month, submission.Year)
+	subject := fmt.Sprintf(translator.Translate("email_rejection_subject", lang), period)
+	// The body is sent as HTML, so the text entered by people is escaped
+	body := fmt.Sprintf(translator.Translate("email_rejection_body", lang),
+		html.EscapeString(submission.Name), period, html.EscapeString(submission.Reviewer), html.EscapeString(submission.Comment))
+
+	if err := h.emailService.SendEmailWithAttachments(subject, body, []string{submission.MemberEmail}, nil, nil); err != nil {
+		log.Printf("Error sending rejection email: %v", err)
+	}
+}
+
+// parsePeriod parses a "YYYY-MM" period, returning zeros when it is empty or
+// invalid so that no period filter applies
+func parsePeriod(period string) (int, int) {
+	t, err := time.Parse("2006-01", period)
+	if err != nil {
+		return 0, 0
+	}
+	return t.Year(), int(t.Month())
+}
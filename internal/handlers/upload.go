@@ -2,35 +2,42 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"timesheet-filler/internal/contextkeys"
 	"timesheet-filler/internal/models"
 	"timesheet-filler/internal/services"
-
-	"github.com/xuri/excelize/v2"
 )
 
 type UploadHandler struct {
 	excelService    *services.ExcelService
+	icalService     *services.ICalService
 	fileStore       *services.FileStore
+	draftStore      *services.DraftStore
 	templateService *services.TemplateService
 	maxUploadSize   int64
 }
 
 func NewUploadHandler(
 	excelService *services.ExcelService,
+	icalService *services.ICalService,
 	fileStore *services.FileStore,
+	draftStore *services.DraftStore,
 	templateService *services.TemplateService,
 	maxUploadSize int64,
 ) *UploadHandler {
 	return &UploadHandler{
 		excelService:    excelService,
+		icalService:     icalService,
 		fileStore:       fileStore,
+		draftStore:      draftStore,
 		templateService: templateService,
 		maxUploadSize:   maxUploadSize,
 	}
@@ -53,7 +60,15 @@ func (h *UploadHandler) UploadFormHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	h.templateService.RenderTemplate(w, "upload.html", nil, http.StatusOK, lang)
+	// Offer to continue unfinished edits of this browser. Reminder emails
+	// link here with the member and month to preselect after the upload.
+	query := r.URL.Query()
+	tmplData := models.UploadTemplateData{
+		Drafts: h.draftStore.List(clientIDFromRequest(r)),
+		Name:   query.Get("name"),
+		Month:  query.Get("month"),
+	}
+	h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusOK, lang)
 }
 
 func (h *UploadHandler) UploadFileHandler(w http.ResponseWriter, r *http.Request) {
@@ -83,66 +98,123 @@ func (h *UploadHandler) UploadFileHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Retrieve the uploaded file
-	file, header, err := r.FormFile("excelFile")
-	if err != nil {
+	// Retrieve the uploaded files. Several attendance exports, e.g. one per
+	// team, are merged into one session.
+	fileHeaders := r.MultipartForm.File["excelFile"]
+	if len(fileHeaders) == 0 {
 		tmplData := models.BaseTemplateData{
 			Error: "Bad Request: Unable to retrieve file.",
 		}
 		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
-		log.Printf("Error retrieving file: %v", err)
+		log.Printf("Error retrieving file: no file uploaded")
 		return
 	}
-	defer file.Close()
 
-	// Log file details
-	log.Printf("Received file: %s (%d bytes)", header.Filename, header.Size)
+	var uploaded []models.SourceFile
+	for _, header := range fileHeaders {
+		// Log file details
+		log.Printf("Received file: %s (%d bytes)", header.Filename, header.Size)
 
-	// Read the file into a buffer
-	buf := &bytes.Buffer{}
-	if _, err := io.Copy(buf, file); err != nil {
-		tmplData := models.BaseTemplateData{
-			Error: "Internal Server Error: Unable to read file.",
+		data, err := readUploadedFile(header)
+		if err != nil {
+			tmplData := models.BaseTemplateData{
+				Error: "Internal Server Error: Unable to read file.",
+			}
+			h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusInternalServerError, lang)
+			log.Printf("Error reading file: %v", err)
+			return
 		}
-		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusInternalServerError, lang)
-		log.Printf("Error reading file: %v", err)
-		return
+		uploaded = append(uploaded, models.SourceFile{Filename: header.Filename, Data: data})
 	}
 
-	fileData := buf.Bytes()
+	// Files added from the select page join the exports of that session
+	fileToken := r.FormValue("fileToken")
+	var files []models.SourceFile
+	var pc services.ParseContext
+	if fileToken != "" {
+		existing, ok := h.fileStore.GetFileData(fileToken)
+		if !ok || existing.Kind != models.SourceKindExcel || existing.Scope != nil {
+			tmplData := models.BaseTemplateData{
+				Error: "Invalid session. Please re-upload your file.",
+			}
+			h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+			return
+		}
+		files = append(files, sourceFiles(existing)...)
+		pc = parseContext(existing)
+	} else if len(uploaded) == 1 {
+		fileData := uploaded[0].Data
 
-	// Parse the Excel file to get the list of names and months
-	names, monthsInt, err := h.excelService.ParseExcelForNamesAndMonths(fileData)
-	if err != nil {
-		// Check if it's a sheet not found error by looking at the error message
-		if strings.Contains(err.Error(), "sheet") && strings.Contains(err.Error(), "does not exist") {
-			// Get available sheets directly from the Excel file
-			srcFile, fileErr := excelize.OpenReader(bytes.NewReader(fileData))
-			if fileErr != nil {
-				log.Printf("Error opening Excel file: %v", fileErr)
-				tmplData := models.BaseTemplateData{
-					Error: "Failed to process Excel file: " + fileErr.Error(),
-				}
-				h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusInternalServerError, lang)
-				return
+		// Calendar exports are an alternative source of entries
+		if services.IsICalendar(fileData) {
+			h.handleCalendarUpload(w, r, fileData, uploaded[0].Filename, lang)
+			return
+		}
+
+		// Previously generated reports are opened again for correction
+		if h.excelService.IsGeneratedReport(fileData) {
+			h.handleReportUpload(w, fileData, lang)
+			return
+		}
+	}
+
+	for _, file := range uploaded {
+		if services.IsICalendar(file.Data) || h.excelService.IsGeneratedReport(file.Data) {
+			tmplData := models.BaseTemplateData{
+				Error: "Bad Request: Calendars and timesheet reports cannot be combined with other files.",
 			}
-			defer srcFile.Close()
+			h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+			return
+		}
+	}
+	files = append(files, uploaded...)
+
+	// Parse the Excel files to get the list of names and months
+	names, monthsInt, err := h.excelService.ParseSourcesForNamesAndMonths(files, pc)
 
-			// Get all available sheets
-			availableSheets := srcFile.GetSheetList()
+	// When the configured sheet is missing, use the sheet that looks like
+	// the attendance export, and only ask the user when that is unclear
+	var snfErr services.SheetNotFoundError
+	var candidates []models.SheetCandidate
+	if errors.As(err, &snfErr) && len(pc.Sheets) == 0 {
+		sheet, detectErr := h.excelService.DetectSourceSheet(files[0].Data)
+		var ambiguousErr services.AmbiguousSheetError
+		switch {
+		case detectErr == nil:
+			log.Printf("Sheet '%s' not found, reading sheet '%s' recognised by its columns", snfErr.SheetName, sheet)
+			pc = services.ParseContext{Sheets: []string{sheet}}
+			names, monthsInt, err = h.excelService.ParseSourcesForNamesAndMonths(files, pc)
+		case errors.As(detectErr, &ambiguousErr):
+			candidates = ambiguousErr.Candidates
+		default:
+			log.Printf("Error detecting source sheet: %v", detectErr)
+		}
+	}
 
+	if err != nil {
+		// Let the user pick another sheet
+		if errors.As(err, &snfErr) {
 			// Store the file data for later use
-			fileToken := h.fileStore.StoreFileData(fileData, nil, nil, "")
+			fileToken := h.fileStore.StoreFileDataEntry(models.FileData{
+				Data:  files[0].Data,
+				Files: files,
+				Kind:  models.SourceKindExcel,
+			})
 
-			// Extract the sheet name from the error message
-			requestedSheet := h.excelService.GetSourceSheet() // Add this getter method
+			// Without a preview, offer the sheets by name
+			if candidates == nil {
+				for _, sheet := range snfErr.AvailableSheets {
+					candidates = append(candidates, models.SheetCandidate{Name: sheet})
+				}
+			}
 
 			// Render the sheet selection template
 			tmplData := models.SelectSheetTemplateData{
 				BaseTemplateData: models.BaseTemplateData{},
 				FileToken:        fileToken,
-				RequestedSheet:   requestedSheet,
-				AvailableSheets:  availableSheets,
+				RequestedSheet:   snfErr.SheetName,
+				AvailableSheets:  snfErr.AvailableSheets,
+				Candidates:       candidates,
 			}
 			h.templateService.RenderTemplate(w, "select_sheet.html", tmplData, http.StatusOK, lang)
 			return
@@ -169,17 +241,172 @@ func (h *UploadHandler) UploadFileHandler(w http.ResponseWriter, r *http.Request
 		defaultMonth = strconv.Itoa(maxMonth)
 	}
 
-	// Store the fileData along with names and months using a unique token
-	fileToken := h.fileStore.StoreFileData(fileData, names, months, "")
+	// Prefer the month requested through a reminder link, if the file has it
+	if requested := r.FormValue("month"); requested != "" {
+		for _, m := range months {
+			if m == requested {
+				defaultMonth = requested
+			}
+		}
+	}
+
+	// Offer the event types of the export for selection
+	eventTypes, err := h.excelService.EventTypes(files, pc)
+	if err != nil {
+		log.Printf("Error reading event types: %v", err)
+	}
+
+	// Report date cells that cannot be read instead of silently dropping them
+	parseFailures, err := h.excelService.DateParseFailures(files, pc)
+	if err != nil {
+		log.Printf("Error checking dates: %v", err)
+	}
+
+	// Store the files along with names and months using a unique token, or
+	// under the token of the session they were added to
+	entry := models.FileData{
+		Data:       files[0].Data,
+		Files:      files,
+		Kind:       models.SourceKindExcel,
+		Names:      names,
+		Months:     months,
+		EventTypes: eventTypes,
+		SheetNames: pc.Sheets,
+	}
+	if fileToken == "" || !h.fileStore.UpdateFileDataEntry(fileToken, entry) {
+		fileToken = h.fileStore.StoreFileDataEntry(entry)
+	}
 
 	// Prepare data for the template
+	tmplData := models.SelectTemplateData{
+		FileToken:     fileToken,
+		Names:         names,
+		Months:        months,
+		EventTypes:    eventTypes,
+		DefaultName:   r.FormValue("name"),
+		DefaultMonth:  defaultMonth,
+		ParseFailures: parseFailures,
+		Files:         sourceFileNames(files),
+		Attendance:    true,
+	}
+
+	// Serve the selection form
+	h.templateService.RenderTemplate(w, "select.html", tmplData, http.StatusOK, lang)
+}
+
+// handleCalendarUpload stores an uploaded .ics file and offers the months it
+// contains. The calendar holds a single member, named from the form or, if
+// left empty, from the file name.
+func (h *UploadHandler) handleCalendarUpload(w http.ResponseWriter, r *http.Request, fileData []byte, filename, lang string) {
+	tableData, _, err := h.icalService.Parse(fileData)
+	if err != nil {
+		log.Printf("Error parsing calendar file: %v", err)
+		tmplData := models.BaseTemplateData{
+			Error: "Internal Server Error: Unable to parse calendar file: " + err.Error(),
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("calendarName"))
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+
+	months, defaultMonth := monthsOfRows(tableData)
+
+	names := []string{name}
+	fileToken := h.fileStore.StoreFileDataEntry(models.FileData{
+		Data:   fileData,
+		Kind:   models.SourceKindCalendar,
+		Names:  names,
+		Months: months,
+	})
+
 	tmplData := models.SelectTemplateData{
 		FileToken:    fileToken,
 		Names:        names,
 		Months:       months,
 		DefaultMonth: defaultMonth,
 	}
-
-	// Serve the selection form
 	h.templateService.RenderTemplate(w, "select.html", tmplData, http.StatusOK, lang)
 }
+
+// handleReportUpload reads back a report generated by this application and
+// opens its entries in the edit page, so it can be corrected and generated
+// again without going back to the attendance export.
+func (h *UploadHandler) handleReportUpload(w http.ResponseWriter, fileData []byte, lang string) {
+	name, tableData, err := h.excelService.ExtractReportData(fileData)
+	if err != nil {
+		log.Printf("Error reading generated report: %v", err)
+		tmplData := models.BaseTemplateData{
+			Error: "Internal Server Error: Unable to read timesheet report: " + err.Error(),
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+
+	months, defaultMonth := monthsOfRows(tableData)
+	if len(months) == 0 {
+		tmplData := models.BaseTemplateData{
+			Error: "Bad Request: The timesheet report contains no entries.",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+
+	names := []string{name}
+	fileToken := h.fileStore.StoreFileDataEntry(models.FileData{
+		Data:   fileData,
+		Kind:   models.SourceKindReport,
+		Names:  names,
+		Months: months,
+	})
+
+	// A report covers a single month, so there is nothing left to select
+	if len(months) != 1 {
+		tmplData := models.SelectTemplateData{
+			FileToken:    fileToken,
+			Names:        names,
+			Months:       months,
+			DefaultMonth: defaultMonth,
+		}
+		h.templateService.RenderTemplate(w, "select.html", tmplData, http.StatusOK, lang)
+		return
+	}
+
+	tmplData := models.EditTemplateData{
+		FileToken: fileToken,
+		Name:      name,
+		Year:      services.ReportYear(tableData),
+		Month:     defaultMonth,
+		TableData: tableData,
+		MaxRows:   h.excelService.ReportMappingFor(tableData).MaxRows,
+		Holidays:  holidaysOfRows(h.excelService.Holidays(), tableData),
+	}
+	h.templateService.RenderTemplate(w, "edit.html", tmplData, http.StatusOK, lang)
+}
+
+// readUploadedFile reads one file of a multipart upload
+func readUploadedFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sourceFileNames returns the names of the uploaded exports
+func sourceFileNames(files []models.SourceFile) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Filename)
+	}
+	return names
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+type VersionHandler struct {
+	versionStore    *services.VersionStore
+	submissionStore *services.SubmissionStore
+	fileStore       *services.FileStore
+	templateService *services.TemplateService
+}
+
+func NewVersionHandler(
+	versionStore *services.VersionStore,
+	submissionStore *services.SubmissionStore,
+	fileStore *services.FileStore,
+	templateService *services.TemplateService,
+) *VersionHandler {
+	return &VersionHandler{
+		versionStore:    versionStore,
+		submissionStore: submissionStore,
+		fileStore:       fileStore,
+		templateService: templateService,
+	}
+}
+
+// VersionsHandler lists the generated versions of a member's report for a
+// period and shows the differences between two of them. Members reach it
+// from the session the report was generated in or from the status page of
+// its submission.
+func (h *VersionHandler) VersionsHandler(w http.ResponseWriter, r *http.Request) {
+	h.renderVersions(w, r, false)
+}
+
+// CoordinatorVersionsHandler shows the versions of any member's report to
+// coordinators
+func (h *VersionHandler) CoordinatorVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	h.renderVersions(w, r, true)
+}
+
+// renderVersions compares two versions of a report. Without explicit
+// versions the latest one is compared with its predecessor.
+func (h *VersionHandler) renderVersions(w http.ResponseWriter, r *http.Request, coordinator bool) {
+	langValue := r.Context().Value(contextkeys.LanguageKey)
+	var lang string
+	if langValue != nil {
+		lang = langValue.(string)
+	} else {
+		lang = "en"
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	name := query.Get("name")
+	year, yearErr := strconv.Atoi(query.Get("year"))
+	month, monthErr := strconv.Atoi(query.Get("month"))
+	if name == "" || yearErr != nil || monthErr != nil || month < 1 || month > 12 {
+		tmplData := models.BaseTemplateData{
+			Error: "Missing or invalid member and period.",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusBadRequest, lang)
+		return
+	}
+
+	if !coordinator && !h.allowed(query, name, year, month) {
+		tmplData := models.BaseTemplateData{
+			Error: "Invalid session. Please re-upload your file.",
+		}
+		h.templateService.RenderTemplate(w, "upload.html", tmplData, http.StatusForbidden, lang)
+		return
+	}
+
+	versions := h.versionStore.List(name, year, month)
+	tmplData := models.VersionsTemplateData{
+		Name:         name,
+		Year:         year,
+		Month:        month,
+		Versions:     versions,
+		FileToken:    query.Get("fileToken"),
+		SubmissionID: query.Get("submission"),
+		Coordinator:  coordinator,
+	}
+
+	if len(versions) > 0 {
+		to := versions[len(versions)-1]
+		from := to
+		if len(versions) > 1 {
+			from = versions[len(versions)-2]
+		}
+		if v, ok := findVersion(versions, query.Get("from")); ok {
+			from = v
+		}
+		if v, ok := findVersion(versions, query.Get("to")); ok {
+			to = v
+		}
+
+		tmplData.From = from
+		tmplData.To = to
+		tmplData.Diff = services.DiffRows(from.TableData, to.TableData)
+	}
+
+	h.templateService.RenderTemplate(w, "versions.html", tmplData, http.StatusOK, lang)
+}
+
+// allowed reports whether a member may see the versions of a period: with a
+// session holding the member and period, see sessionShowsPeriod, or with the
+// ID of a submission for the period
+func (h *VersionHandler) allowed(query url.Values, name string, year, month int) bool {
+	if fileData, ok := h.fileStore.GetFileData(query.Get("fileToken")); ok {
+		return sessionShowsPeriod(fileData, name, year, month)
+	}
+	if submission, ok := h.submissionStore.Get(query.Get("submission")); ok {
+		return submission.Name == name && submission.Year == year && submission.Month == month
+	}
+	return false
+}
+
+// findVersion looks up a version of the listed period by its ID, so that a
+// request cannot mix in versions of another member
+func findVersion(versions []models.ReportVersion, id string) (models.ReportVersion, bool) {
+	for _, v := range versions {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return models.ReportVersion{}, false
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/i18n"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/services"
+)
+
+// newTestTemplateService renders the templates of the repository
+func newTestTemplateService(t *testing.T) *services.TemplateService {
+	t.Helper()
+	translator, err := i18n.NewTranslator("../../translations", "en")
+	if err != nil {
+		t.Fatalf("failed to initialize translator: %v", err)
+	}
+	return services.NewTemplateService("../../templates", translator)
+}
+
+// serveTest calls a handler with a request in English
+func serveTest(handler http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.LanguageKey, "en"))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestVersionsAccess(t *testing.T) {
+	versionStore := services.NewVersionStore("")
+	fileStore := services.NewFileStore(time.Hour, time.Hour)
+	handler := NewVersionHandler(versionStore, services.NewSubmissionStore(""), fileStore, newTestTemplateService(t))
+
+	for _, name := range []string{"Novák Jan", "Dvořák Petr"} {
+		versionStore.Add(name, 2024, 3, nil, "report.xlsx")
+		versionStore.Add(name, 2023, 3, nil, "report.xlsx")
+	}
+
+	upload := fileStore.StoreFileDataEntry(models.FileData{
+		Kind:   models.SourceKindCalendar,
+		Names:  []string{"Novák Jan"},
+		Months: []string{"3"},
+	})
+	link := fileStore.StoreFileDataEntry(models.FileData{
+		Kind:  models.SourceKindExcel,
+		Names: []string{"Novák Jan"},
+		Scope: &models.MemberScope{Name: "Novák Jan", Year: 2024, Month: 3},
+	})
+
+	tests := []struct {
+		name      string
+		fileToken string
+		member    string
+		year      string
+		want      int
+	}{
+		{"member of the upload", upload, "Novák Jan", "2024", http.StatusOK},
+		{"member missing from the upload", upload, "Dvořák Petr", "2024", http.StatusForbidden},
+		{"period of the link", link, "Novák Jan", "2024", http.StatusOK},
+		{"other year of the link", link, "Novák Jan", "2023", http.StatusForbidden},
+		{"other member of the link", link, "Dvořák Petr", "2024", http.StatusForbidden},
+		{"unknown session", "unknown", "Novák Jan", "2024", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			query.Set("fileToken", tt.fileToken)
+			query.Set("name", tt.member)
+			query.Set("year", tt.year)
+			query.Set("month", "3")
+
+			rec := serveTest(handler.VersionsHandler, httptest.NewRequest(http.MethodGet, "/versions?"+query.Encode(), nil))
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package holidays
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Rule returns the public holidays of a year, keyed by date (YYYY-MM-DD)
+type Rule func(year int) map[string]string
+
+var (
+	rules      = map[string]Rule{"CZ": czechHolidays}
+	rulesMutex sync.RWMutex
+)
+
+// Register makes the holidays of another country available to New
+func Register(country string, rule Rule) {
+	rulesMutex.Lock()
+	rules[strings.ToUpper(country)] = rule
+	rulesMutex.Unlock()
+}
+
+// Calendar answers whether a date is a public holiday. A nil Calendar knows
+// no holidays.
+type Calendar struct {
+	rule  Rule
+	extra map[string]string
+	years map[int]map[string]string
+	mutex sync.Mutex
+}
+
+// New returns the calendar of a country (none when empty) extended with extra
+// days given as "YYYY-MM-DD" or "YYYY-MM-DD=Name"
+func New(country string, extraDays []string) (*Calendar, error) {
+	c := &Calendar{
+		extra: make(map[string]string),
+		years: make(map[int]map[string]string),
+	}
+
+	if country != "" {
+		rulesMutex.RLock()
+		rule, ok := rules[strings.ToUpper(country)]
+		rulesMutex.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("no holiday calendar for country %q", country)
+		}
+		c.rule = rule
+	}
+
+	for _, day := range extraDays {
+		day = strings.TrimSpace(day)
+		if day == "" {
+			continue
+		}
+		date, name, _ := strings.Cut(day, "=")
+		date = strings.TrimSpace(date)
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf("invalid extra holiday %q: %w", day, err)
+		}
+		c.extra[date] = strings.TrimSpace(name)
+	}
+
+	return c, nil
+}
+
+// Holiday returns the name of the holiday on date, if it is one. Extra days
+// without a name return an empty name.
+func (c *Calendar) Holiday(date time.Time) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	name, ok := c.Year(date.Year())[date.Format(dateLayout)]
+	return name, ok
+}
+
+// Year returns all holidays of a year, keyed by date (YYYY-MM-DD)
+func (c *Calendar) Year(year int) map[string]string {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if days, ok := c.years[year]; ok {
+		return days
+	}
+
+	days := make(map[string]string)
+	if c.rule != nil {
+		for date, name := range c.rule(year) {
+			days[date] = name
+		}
+	}
+	prefix := fmt.Sprintf("%04d-", year)
+	for date, name := range c.extra {
+		if strings.HasPrefix(date, prefix) {
+			days[date] = name
+		}
+	}
+
+	c.years[year] = days
+	return days
+}
+
+// IsWeekend reports whether date falls on a Saturday or Sunday
+func IsWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
+
+// Easter returns Easter Sunday of a year in the Gregorian calendar
+func Easter(year int) time.Time {
+	// Anonymous Gregorian algorithm (Meeus/Jones/Butcher)
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// czechHolidays are the public holidays of the Czech Republic
+func czechHolidays(year int) map[string]string {
+	days := map[string]string{
+		fmt.Sprintf("%04d-01-01", year): "Den obnovy samostatného českého státu",
+		fmt.Sprintf("%04d-05-01", year): "Svátek práce",
+		fmt.Sprintf("%04d-05-08", year): "Den vítězství",
+		fmt.Sprintf("%04d-07-05", year): "Den slovanských věrozvěstů Cyrila a Metoděje",
+		fmt.Sprintf("%04d-07-06", year): "Den upálení mistra Jana Husa",
+		fmt.Sprintf("%04d-09-28", year): "Den české státnosti",
+		fmt.Sprintf("%04d-10-28", year): "Den vzniku samostatného československého státu",
+		fmt.Sprintf("%04d-11-17", year): "Den boje za svobodu a demokracii",
+		fmt.Sprintf("%04d-12-24", year): "Štědrý den",
+		fmt.Sprintf("%04d-12-25", year): "1. svátek vánoční",
+		fmt.Sprintf("%04d-12-26", year): "2. svátek vánoční",
+	}
+
+	easter := Easter(year)
+	days[easter.AddDate(0, 0, 1).Format(dateLayout)] = "Velikonoční pondělí"
+	// Good Friday is a public holiday since 2016
+	if year >= 2016 {
+		days[easter.AddDate(0, 0, -2).Format(dateLayout)] = "Velký pátek"
+	}
+
+	return days
+}
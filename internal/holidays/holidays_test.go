@@ -0,0 +1,93 @@
+package holidays
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEaster(t *testing.T) {
+	tests := map[int]string{
+		2015: "2015-04-05",
+		2023: "2023-04-09",
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+	}
+
+	for year, want := range tests {
+		if got := Easter(year).Format(dateLayout); got != want {
+			t.Errorf("Expected Easter %d to be %s, got %s", year, want, got)
+		}
+	}
+}
+
+func TestCzechCalendar(t *testing.T) {
+	calendar, err := New("cz", []string{"2024-12-23=Ředitelské volno", "2024-12-31"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		date    string
+		holiday bool
+		name    string
+	}{
+		{"2024-01-01", true, "Den obnovy samostatného českého státu"},
+		{"2024-03-29", true, "Velký pátek"},
+		{"2024-04-01", true, "Velikonoční pondělí"},
+		{"2024-11-17", true, "Den boje za svobodu a demokracii"},
+		{"2024-12-23", true, "Ředitelské volno"},
+		{"2024-12-31", true, ""},
+		{"2024-04-02", false, ""},
+		{"2025-12-23", false, ""},
+		{"2015-04-03", false, ""},
+	}
+
+	for _, tt := range tests {
+		date, _ := time.Parse(dateLayout, tt.date)
+		name, ok := calendar.Holiday(date)
+		if ok != tt.holiday || name != tt.name {
+			t.Errorf("Expected %s to be holiday=%t %q, got holiday=%t %q", tt.date, tt.holiday, tt.name, ok, name)
+		}
+	}
+
+	if got := len(calendar.Year(2024)); got != 15 {
+		t.Errorf("Expected 15 holidays in 2024, got %d", got)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New("XX", nil); err == nil {
+		t.Error("Expected an error for an unknown country")
+	}
+	if _, err := New("CZ", []string{"24.12.2024"}); err == nil {
+		t.Error("Expected an error for an invalid extra day")
+	}
+
+	var calendar *Calendar
+	if _, ok := calendar.Holiday(time.Now()); ok {
+		t.Error("Expected a nil calendar to know no holidays")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", func(year int) map[string]string {
+		return map[string]string{"2024-06-01": "Test day"}
+	})
+
+	calendar, err := New("TEST", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if name, ok := calendar.Holiday(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)); !ok || name != "Test day" {
+		t.Errorf("Expected the registered holiday, got %q", name)
+	}
+}
+
+func TestIsWeekend(t *testing.T) {
+	if !IsWeekend(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Saturday to be a weekend")
+	}
+	if IsWeekend(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Monday not to be a weekend")
+	}
+}
@@ -71,3 +71,28 @@ func (t *Translator) TranslateMap(keys map[string]string, lang string) map[strin
 	}
 	return result
 }
+
+// WithOverrides returns a translator that prefers the given translations,
+// keyed by language and then key, and falls back to t for all others
+func (t *Translator) WithOverrides(overrides map[string]map[string]string) *Translator {
+	merged := make(map[string]map[string]string, len(t.translations))
+	for lang, translations := range t.translations {
+		merged[lang] = make(map[string]string, len(translations))
+		for key, value := range translations {
+			merged[lang][key] = value
+		}
+	}
+	for lang, translations := range overrides {
+		if merged[lang] == nil {
+			merged[lang] = make(map[string]string, len(translations))
+		}
+		for key, value := range translations {
+			merged[lang][key] = value
+		}
+	}
+
+	return &Translator{
+		translations: merged,
+		defaultLang:  t.defaultLang,
+	}
+}
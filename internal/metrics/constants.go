@@ -5,6 +5,7 @@ const (
 	StageSelect   = "select"
 	StageEdit     = "edit"
 	StageProcess  = "process"
+	StagePDF      = "pdf"
 	StageStorage  = "storage"
 	StageDownload = "download"
 	StageEmail    = "email"
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// BasicAuthMiddleware protects routes with a single username and password.
+// Without a configured password the protected routes are disabled.
+type BasicAuthMiddleware struct {
+	realm    string
+	username string
+	password string
+}
+
+func NewBasicAuthMiddleware(realm, username, password string) *BasicAuthMiddleware {
+	return &BasicAuthMiddleware{
+		realm:    realm,
+		username: username,
+		password: password,
+	}
+}
+
+func (m *BasicAuthMiddleware) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.password == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(m.username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(m.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+m.realm+`", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
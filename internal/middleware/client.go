@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/utils"
+)
+
+// ClientMiddleware identifies a browser with a long-lived random cookie, so
+// that server-side state such as drafts can be tied to it without accounts.
+type ClientMiddleware struct {
+	cookieName string
+	maxAge     int
+}
+
+func NewClientMiddleware(cookieName string, maxAge int) *ClientMiddleware {
+	return &ClientMiddleware{
+		cookieName: cookieName,
+		maxAge:     maxAge,
+	}
+}
+
+func (m *ClientMiddleware) IdentifyClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var clientID string
+		if cookie, err := r.Cookie(m.cookieName); err == nil && cookie.Value != "" {
+			clientID = cookie.Value
+		} else {
+			clientID = utils.GenerateToken()
+		}
+
+		// Refresh the cookie on every request to keep active clients alive
+		http.SetCookie(w, &http.Cookie{
+			Name:     m.cookieName,
+			Value:    clientID,
+			Path:     "/",
+			MaxAge:   m.maxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		ctx := context.WithValue(r.Context(), contextkeys.ClientIDKey, clientID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+)
+
+// TenantMiddleware routes requests to the handler of the tenant they are
+// addressed to, by host name first and then by path prefix. Requests matching
+// no tenant are served by the default handler.
+type TenantMiddleware struct {
+	fallback http.Handler
+	hosts    map[string]*tenantRoute
+	prefixes []*tenantRoute
+}
+
+type tenantRoute struct {
+	id      string
+	prefix  string
+	handler http.Handler
+}
+
+func NewTenantMiddleware(fallback http.Handler) *TenantMiddleware {
+	return &TenantMiddleware{
+		fallback: fallback,
+		hosts:    make(map[string]*tenantRoute),
+	}
+}
+
+// Add registers the handler serving a tenant
+func (m *TenantMiddleware) Add(tenant models.Tenant, handler http.Handler) {
+	route := &tenantRoute{
+		id:      tenant.ID,
+		prefix:  tenant.PathPrefix,
+		handler: handler,
+	}
+	for _, host := range tenant.Hosts {
+		m.hosts[strings.ToLower(host)] = route
+	}
+	if route.prefix != "" {
+		m.prefixes = append(m.prefixes, route)
+	}
+}
+
+func (m *TenantMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := m.route(r)
+	if route == nil {
+		m.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), contextkeys.TenantKey, route.id)
+	r = r.WithContext(ctx)
+
+	if route.prefix != "" {
+		// Handlers know their routes without the prefix, and redirect to them
+		if path, ok := trimPathPrefix(r.URL.Path, route.prefix); ok {
+			r.URL.Path = path
+			r.URL.RawPath = ""
+		}
+		w = &prefixedRedirectWriter{ResponseWriter: w, prefix: route.prefix}
+	}
+
+	route.handler.ServeHTTP(w, r)
+}
+
+// route finds the tenant of a request. A tenant's own host wins over the
+// path prefix of another, so tenants cannot reach each other's pages.
+func (m *TenantMiddleware) route(r *http.Request) *tenantRoute {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if route, ok := m.hosts[strings.ToLower(host)]; ok {
+		return route
+	}
+
+	for _, route := range m.prefixes {
+		if _, ok := trimPathPrefix(r.URL.Path, route.prefix); ok {
+			return route
+		}
+	}
+	return nil
+}
+
+// trimPathPrefix removes prefix from path when path is within it
+func trimPathPrefix(path, prefix string) (string, bool) {
+	if path == prefix {
+		return "/", true
+	}
+	if strings.HasPrefix(path, prefix+"/") {
+		return strings.TrimPrefix(path, prefix), true
+	}
+	return path, false
+}
+
+// prefixedRedirectWriter adds the tenant's path prefix to redirects to
+// absolute paths
+type prefixedRedirectWriter struct {
+	http.ResponseWriter
+	prefix string
+}
+
+func (w *prefixedRedirectWriter) WriteHeader(code int) {
+	location := w.Header().Get("Location")
+	if strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") {
+		w.Header().Set("Location", w.prefix+location)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
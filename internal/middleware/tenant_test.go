@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"timesheet-filler/internal/contextkeys"
+	"timesheet-filler/internal/models"
+)
+
+// tenantApp answers with the tenant and path it saw. Sessions staying
+// within their tenant are tested with the real handlers in cmd/server.
+func tenantApp() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/done" {
+			http.Redirect(w, r, "/admin/templates", http.StatusSeeOther)
+			return
+		}
+		tenant, _ := r.Context().Value(contextkeys.TenantKey).(string)
+		w.Write([]byte(tenant + " " + r.URL.Path))
+	})
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	router := NewTenantMiddleware(tenantApp())
+	router.Add(models.Tenant{ID: "partner", Hosts: []string{"partner.example.com"}, PathPrefix: "/partner"}, tenantApp())
+	router.Add(models.Tenant{ID: "third", Hosts: []string{"third.example.com"}}, tenantApp())
+
+	tests := []struct {
+		name   string
+		host   string
+		path   string
+		status int
+		body   string
+	}{
+		{"default tenant", "club.example.com", "/upload", http.StatusOK, " /upload"},
+		{"path prefix", "club.example.com", "/partner/upload", http.StatusOK, "partner /upload"},
+		{"prefix root", "club.example.com", "/partner", http.StatusOK, "partner /"},
+		{"prefix of a longer segment", "club.example.com", "/partners", http.StatusOK, " /partners"},
+		{"host", "partner.example.com:8080", "/upload", http.StatusOK, "partner /upload"},
+		{"host with its own prefix", "Partner.Example.com", "/partner/upload", http.StatusOK, "partner /upload"},
+		{"host wins over another prefix", "third.example.com", "/partner/upload", http.StatusOK, "third /partner/upload"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, rec.Code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.body, rec.Body.String())
+		}
+	}
+
+	// Redirects stay within the tenant's prefix
+	for path, want := range map[string]string{
+		"/partner/done": "/partner/admin/templates",
+		"/done":         "/admin/templates",
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if location := rec.Header().Get("Location"); location != want {
+			t.Errorf("Expected %s to redirect to %s, got %s", path, want, location)
+		}
+	}
+}
@@ -9,40 +9,108 @@ type BaseTemplateData struct {
 	Error string
 }
 
+type UploadTemplateData struct {
+	BaseTemplateData
+	Drafts []Draft
+	Name   string
+	Month  string
+}
+
 type SelectSheetTemplateData struct {
 	BaseTemplateData
 	FileToken       string
 	RequestedSheet  string
 	AvailableSheets []string
+	// Sheets to choose from, best matching first
+	Candidates []SheetCandidate
+}
+
+// SheetCandidate is a sheet of an uploaded workbook offered as the source
+// sheet, with how many of the expected columns its header row has in place
+type SheetCandidate struct {
+	Name     string
+	Score    int
+	MaxScore int
+	// First rows of the sheet, header included
+	Preview [][]string
 }
 
 type SelectTemplateData struct {
 	BaseTemplateData
-	FileToken    string
-	Names        []string
-	Months       []string
-	DefaultMonth string
+	FileToken     string
+	Names         []string
+	Months        []string
+	EventTypes    []string
+	DefaultName   string
+	DefaultMonth  string
+	ParseFailures []ParseFailure
+	Files         []string
+	// Attendance exports offer to include events with unconfirmed attendance
+	Attendance bool
+}
+
+type DiagnosticsTemplateData struct {
+	BaseTemplateData
+	FileToken   string
+	Diagnostics SourceDiagnostics
 }
 
 type EditTemplateData struct {
 	BaseTemplateData
-	FileToken string
-	Name      string
-	Month     string
-	TableData []TableRow
+	FileToken   string
+	Name        string
+	Year        int
+	Month       string
+	TableData   []TableRow
+	Issues      []ValidationIssue
+	RowIssues   map[int][]ValidationIssue
+	CanOverride bool
+	MaxRows     int
+	HasDraft    bool
+	DraftSaved  time.Time
+	// Public holidays of the years shown, keyed by date (YYYY-MM-DD)
+	Holidays map[string]string
+	// Event types selected on the select page, empty for all
+	EventTypes []string
+	// Whether events with unconfirmed attendance were included, and how
+	// many events of the export were left out, by reason
+	IncludeTentative bool
+	Excluded         map[string]int
 }
 
 type DownloadTemplateData struct {
 	BaseTemplateData
-	DownloadToken string
-	FileName      string
-	FileToken     string
-	Name          string
-	Month         string
-	EmailOptions  EmailOptions
-	EmailSent     bool
-	EmailError    string
-	EmailEnabled  bool
+	DownloadToken    string
+	FileName         string
+	PDFDownloadToken string
+	PDFFileName      string
+	FileToken        string
+	Name             string
+	Month            string
+	EmailOptions     EmailOptions
+	EmailSent        bool
+	EmailError       string
+	EmailEnabled     bool
+	Totals           ReportTotals
+	SheetCount       int
+	VersionID        string
+	VersionNumber    int
+	Year             int
+}
+
+type VersionsTemplateData struct {
+	BaseTemplateData
+	Name     string
+	Year     int
+	Month    int
+	Versions []ReportVersion
+	From     ReportVersion
+	To       ReportVersion
+	Diff     []RowDiff
+	// Session or submission the page was opened from, kept when comparing
+	FileToken    string
+	SubmissionID string
+	Coordinator  bool
 }
 
 type TableRow struct {
@@ -50,21 +118,363 @@ type TableRow struct {
 	StartTime string
 	EndTime   string
 	Note      string
+	// Type of the source event, e.g. "trénink", empty for manual rows
+	EventType string `json:",omitempty"`
+	// Set for events whose attendance was not confirmed in the export
+	Tentative bool `json:",omitempty"`
+	// Name of the export the row was read from
+	Origin string `json:",omitempty"`
+}
+
+// ReportMapping describes where ProcessExcelFile writes values in the report
+// template. Columns are spreadsheet column letters, cells are A1 references.
+type ReportMapping struct {
+	TargetSheet     string
+	FirstNameCell   string
+	LastNameCell    string
+	StartRow        int
+	MaxRows         int
+	DateColumn      string
+	StartTimeColumn string
+	EndTimeColumn   string
+	NoteColumn      string
+	HoursColumn     string
+	TotalHoursCell  string
+	TimeStyleID     int
+
+	// Column answering whether a row is on a public holiday ("ano"/"ne") and
+	// the fill of date cells on holidays and weekends. Both are only used
+	// when a holiday calendar is configured.
+	HolidayColumn   string
+	DayOffFillColor string
+
+	// Cells stamped when a coordinator approves the report. Label cells are
+	// optional and filled with fixed captions when set.
+	ApprovedByCell      string
+	ApprovedAtCell      string
+	ApprovedByLabelCell string
+	ApprovedAtLabelCell string
+}
+
+type ReportTotals struct {
+	RowHours   []float64
+	RowCount   int
+	TotalHours float64
+	// Hours per event type, only for rows that have one
+	TypeHours map[string]float64
 }
 
+type ValidationIssue struct {
+	Row      int
+	Code     string
+	Severity string
+	Args     []interface{}
+}
+
+// ParseFailure is a date cell of an uploaded export that could not be read,
+// addressed as in Excel (row 2 is the first data row)
+type ParseFailure struct {
+	File   string
+	Sheet  string
+	Row    int
+	Column string
+	Value  string
+}
+
+// SourceColumn is a field of the attendance export and the column it is
+// read from
+type SourceColumn struct {
+	Field  string `json:"field"`
+	Column string `json:"column"`
+	Header string `json:"header"`
+}
+
+// SkippedRow is a row of the attendance export no timesheet takes an event
+// from, addressed as in Excel
+type SkippedRow struct {
+	File   string `json:"file"`
+	Sheet  string `json:"sheet"`
+	Row    int    `json:"row"`
+	Member string `json:"member"`
+	Reason string `json:"reason"`
+}
+
+// SourceDiagnostics summarizes how the uploaded attendance exports of a
+// session are read
+type SourceDiagnostics struct {
+	Sheets      []string       `json:"sheets"`
+	Files       []string       `json:"files"`
+	Columns     []SourceColumn `json:"columns"`
+	TotalRows   int            `json:"totalRows"`
+	UsedRows    int            `json:"usedRows"`
+	Skipped     map[string]int `json:"skipped"`
+	SkippedRows []SkippedRow   `json:"skippedRows"`
+}
+
+// SourceFile is an uploaded attendance export
+type SourceFile struct {
+	Filename string
+	Data     []byte
+}
+
+// Kinds of uploaded source files
+const (
+	SourceKindExcel    = "excel"
+	SourceKindCalendar = "calendar"
+	SourceKindReport   = "report"
+)
+
 type FileData struct {
-	Data      []byte
-	Names     []string
-	Months    []string
-	SheetName string
+	Data []byte
+	// All attendance exports of the session, Data is the first of them
+	Files      []SourceFile
+	Kind       string
+	Names      []string
+	Months     []string
+	EventTypes []string
+	// Sheets chosen on the sheet selection page, names or patterns
+	SheetNames []string
+	// Set for sessions opened from a personal link
+	Scope     *MemberScope
 	Timestamp time.Time
 }
 
+// MemberScope limits a session to the timesheet of one member for one month
+type MemberScope struct {
+	Name  string
+	Year  int
+	Month int
+}
+
+// PersonalLink is a signed link opening a member's own timesheet for a month
+type PersonalLink struct {
+	Name      string
+	Year      int
+	Month     int
+	ExpiresAt time.Time
+	URL       string
+	// Address the link is emailed to, empty when none is known
+	Email string
+}
+
+// Draft is an unfinished edit of a member's timesheet for one month, saved
+// from the edit page so it survives expired sessions and closed tabs
+type Draft struct {
+	ClientID  string
+	Name      string
+	Year      int
+	Month     string
+	FileToken string
+	TableData []TableRow
+	UpdatedAt time.Time
+}
+
+// ReportVersion is one generated report of a member for a period. Versions
+// are numbered per member and period in the order they were generated.
+type ReportVersion struct {
+	ID         string
+	Name       string
+	Year       int
+	Month      int
+	Number     int
+	TableData  []TableRow
+	TotalHours float64
+	FileName   string
+	CreatedAt  time.Time
+	Emailed    bool
+	EmailedAt  time.Time
+}
+
+// RowDiff describes how a table row changed between two report versions
+type RowDiff struct {
+	Kind string
+	Old  TableRow
+	New  TableRow
+}
+
+// Submission states
+const (
+	SubmissionPending    = "pending"
+	SubmissionApproved   = "approved"
+	SubmissionRejected   = "rejected"
+	SubmissionSuperseded = "superseded"
+)
+
+// Submission is a report version handed in by a member for approval
+type Submission struct {
+	ID          string
+	VersionID   string
+	Name        string
+	Year        int
+	Month       int
+	MemberEmail string
+	Status      string
+	SubmittedAt time.Time
+	Reviewer    string
+	Comment     string
+	ReviewedAt  time.Time
+}
+
+type SubmissionTemplateData struct {
+	BaseTemplateData
+	Submission Submission
+	Version    ReportVersion
+}
+
+type SubmissionsTemplateData struct {
+	BaseTemplateData
+	Status      string
+	Period      string
+	Submissions []Submission
+	Versions    map[string]ReportVersion
+	Reviewer    string
+}
+
+// ReportTemplate is a report template uploaded by an admin together with
+// its field mapping. Once active, it is used for the reports of periods
+// within its validity range.
+type ReportTemplate struct {
+	ID       string
+	Name     string
+	Filename string
+	Mapping  ReportMapping
+	Active   bool
+	// First and last day of the report periods it applies to, open ended
+	// when zero
+	ValidFrom  time.Time
+	ValidTo    time.Time
+	UploadedAt time.Time
+}
+
+// Covers reports whether the template applies to the report period starting
+// at period
+func (rt ReportTemplate) Covers(period time.Time) bool {
+	if !rt.Active {
+		return false
+	}
+	if !rt.ValidFrom.IsZero() && period.Before(rt.ValidFrom) {
+		return false
+	}
+	return rt.ValidTo.IsZero() || !period.After(rt.ValidTo)
+}
+
+type ReportTemplatesTemplateData struct {
+	BaseTemplateData
+	Templates []ReportTemplate
+	// Mapping prefilled in the upload form
+	Mapping ReportMapping
+	// Problems of the last uploaded template, which was not stored
+	Issues []ValidationIssue
+}
+
+// MemberProfile is what is known about a member beyond the name the
+// attendance export lists them under, which the profile is keyed by
+type MemberProfile struct {
+	Member string
+	// Legal name as filled into reports
+	FirstName string
+	LastName  string
+	Email     string
+	Language  string
+	Team      string
+	// Where the member's reports are emailed instead of the default
+	// recipients
+	Recipients []string
+	UpdatedAt  time.Time
+}
+
+type ProfilesTemplateData struct {
+	BaseTemplateData
+	Profiles []MemberProfile
+	// Profile shown in the form, an existing one when editing
+	Profile MemberProfile
+	Editing bool
+}
+
+type PersonalLinksTemplateData struct {
+	BaseTemplateData
+	Period       string
+	Links        []PersonalLink
+	EmailEnabled bool
+	// Number of links emailed, shown after sending them
+	Sent     int
+	ShowSent bool
+}
+
+// Dashboard states of a member's report for a period
+const (
+	DashboardMissing   = "missing"
+	DashboardGenerated = "generated"
+	DashboardEmailed   = "emailed"
+)
+
+// DashboardRow summarizes one member's attendance and report for a period
+type DashboardRow struct {
+	Name             string
+	Team             string
+	Events           int
+	Hours            float64
+	Versions         int
+	LastGenerated    time.Time
+	Emailed          bool
+	SubmissionStatus string
+	Status           string
+}
+
+type DashboardTemplateData struct {
+	BaseTemplateData
+	FileToken string
+	Period    string
+	Status    string
+	Team      string
+	Search    string
+	Rows      []DashboardRow
+	Counts    map[string]int
+	// Teams of the members' profiles, offered as a filter
+	Teams []string
+}
+
 type TemplateData struct {
 	Data        interface{}
 	CurrentYear int
 	CurrentPage string
 	Language    string
+	Branding    Branding
+}
+
+// Branding is how the pages of a tenant differ from the default look. Empty
+// fields keep the default.
+type Branding struct {
+	LogoURL     string `json:"logoUrl"`
+	AccentColor string `json:"accentColor"`
+}
+
+// Tenant is an organisation sharing the deployment. Requests are routed to it
+// by host name or path prefix, and it keeps its own sessions and data. Empty
+// settings fall back to the global configuration.
+type Tenant struct {
+	ID         string   `json:"id"`
+	Hosts      []string `json:"hosts"`
+	PathPrefix string   `json:"pathPrefix"`
+
+	SheetName    string         `json:"sheetName"`
+	TemplatePath string         `json:"templatePath"`
+	Mapping      *ReportMapping `json:"mapping"`
+
+	EmailFromName   string   `json:"emailFromName"`
+	EmailFromEmail  string   `json:"emailFromEmail"`
+	EmailRecipients []string `json:"emailRecipients"`
+	MemberEmails    []string `json:"memberEmails"`
+	PublicURL       string   `json:"publicUrl"`
+
+	CoordinatorUser string `json:"coordinatorUsername"`
+	CoordinatorPass string `json:"coordinatorPassword"`
+	AdminUser       string `json:"adminUsername"`
+	AdminPass       string `json:"adminPassword"`
+
+	Branding Branding `json:"branding"`
+	// Translation overrides by language and key, e.g. the app title
+	Translations map[string]map[string]string `json:"translations"`
 }
 
 type TempFileEntry struct {
@@ -82,7 +492,12 @@ type HealthCheckResponse struct {
 	Status string `json:"status"`
 }
 
+type DraftSaveResponse struct {
+	SavedAt string `json:"savedAt"`
+}
+
 type EmailOptions struct {
-	SendToSelf bool
-	UserEmail  string
+	SendToSelf       bool
+	UserEmail        string
+	AttachmentFormat string
 }
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"strings"
+
+	"timesheet-filler/internal/utils"
+)
+
+// Attendance statuses of a row in the attendance export
+const (
+	AttendanceConfirmed   = "confirmed"
+	AttendanceDeclined    = "declined"
+	AttendanceUnconfirmed = "unconfirmed"
+)
+
+// AttendanceRules maps the values of the attendance column to a status.
+// Values are compared ignoring case and diacritics; values in neither list
+// are unconfirmed.
+type AttendanceRules struct {
+	Accepted []string
+	Declined []string
+}
+
+// DefaultAttendanceRules understands Czech and English exports
+func DefaultAttendanceRules() AttendanceRules {
+	return AttendanceRules{
+		Accepted: []string{"ano", "yes", "y", "true", "1", "x", "✓", "✔"},
+		Declined: []string{"ne", "no", "n", "false", "0", "✗", "✘"},
+	}
+}
+
+// Status returns the attendance status of a cell value
+func (r AttendanceRules) Status(value string) string {
+	normalized := normalizeAttendance(value)
+	for _, accepted := range r.Accepted {
+		if normalizeAttendance(accepted) == normalized {
+			return AttendanceConfirmed
+		}
+	}
+	for _, declined := range r.Declined {
+		if normalizeAttendance(declined) == normalized {
+			return AttendanceDeclined
+		}
+	}
+	return AttendanceUnconfirmed
+}
+
+func normalizeAttendance(value string) string {
+	return strings.ToLower(utils.RemoveDiacritics(strings.TrimSpace(value)))
+}
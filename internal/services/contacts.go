@@ -0,0 +1,49 @@
+package services
+
+// MemberContacts finds the email address and preferred language of members
+// for the emails sent to them
+type MemberContacts struct {
+	profiles        *ProfileStore
+	emails          map[string]string
+	submissionStore *SubmissionStore
+}
+
+// NewMemberContacts combines member profiles, configured "name=email"
+// addresses as returned by ParseMemberEmails and the addresses of past
+// submissions. Any of them may be nil.
+func NewMemberContacts(profiles *ProfileStore, memberEmails map[string]string, submissionStore *SubmissionStore) *MemberContacts {
+	// Look members up ignoring case and diacritics
+	emails := make(map[string]string, len(memberEmails))
+	for name, email := range memberEmails {
+		emails[normalizeMemberName(name)] = email
+	}
+
+	return &MemberContacts{
+		profiles:        profiles,
+		emails:          emails,
+		submissionStore: submissionStore,
+	}
+}
+
+// Email prefers the address of the member's profile, then the configured
+// one, over the one from the member's last submission
+func (mc *MemberContacts) Email(name string) (string, bool) {
+	if profile, ok := mc.profiles.Get(name); ok && profile.Email != "" {
+		return profile.Email, true
+	}
+	if email, ok := mc.emails[normalizeMemberName(name)]; ok {
+		return email, true
+	}
+	if mc.submissionStore == nil {
+		return "", false
+	}
+	return mc.submissionStore.MemberEmail(name)
+}
+
+// Language returns the language of the member's profile, or the fallback
+func (mc *MemberContacts) Language(name, fallback string) string {
+	if profile, ok := mc.profiles.Get(name); ok && profile.Language != "" {
+		return profile.Language
+	}
+	return fallback
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression
+// (minute hour day-of-month month day-of-week)
+type CronSchedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+	// Like cron, when both day fields are restricted a time matches either
+	daysAny     bool
+	weekdaysAny bool
+}
+
+// ParseCronSchedule parses an expression such as "0 9 2 * *". Fields accept
+// "*", single values, ranges ("1-5"), lists ("1,15") and steps ("*/15").
+// Day-of-week 0 and 7 both mean Sunday.
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	bounds := []struct {
+		name     string
+		min, max int
+	}{
+		{"minute", 0, 59},
+		{"hour", 0, 23},
+		{"day of month", 1, 31},
+		{"month", 1, 12},
+		{"day of week", 0, 7},
+	}
+
+	sets := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %s: %w", expr, bounds[i].name, err)
+		}
+		sets[i] = set
+	}
+
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &CronSchedule{
+		minutes:     sets[0],
+		hours:       sets[1],
+		days:        sets[2],
+		months:      sets[3],
+		weekdays:    sets[4],
+		daysAny:     strings.HasPrefix(fields[2], "*"),
+		weekdaysAny: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rangePart, stepPart, ok := strings.Cut(part, "/"); ok {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+			part, step = rangePart, s
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			startPart, endPart, isRange := strings.Cut(part, "-")
+			start, err := strconv.Atoi(startPart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", startPart)
+			}
+			lo, hi = start, start
+			if isRange {
+				end, err := strconv.Atoi(endPart)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q", endPart)
+				}
+				hi = end
+			} else if step > 1 {
+				// "5/15" runs from 5 to the end of the range
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first time after t that matches the schedule, in the
+// location of t. It returns the zero time if nothing matches within five years.
+func (cs *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !cs.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !cs.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !cs.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !cs.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (cs *CronSchedule) matchesDay(t time.Time) bool {
+	dayMatch := cs.days[t.Day()]
+	weekdayMatch := cs.weekdays[int(t.Weekday())]
+
+	switch {
+	case cs.daysAny && cs.weekdaysAny:
+		return true
+	case cs.daysAny:
+		return weekdayMatch
+	case cs.weekdaysAny:
+		return dayMatch
+	default:
+		return dayMatch || weekdayMatch
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 9 2 * *", time.Date(2024, 3, 15, 10, 0, 0, 0, prague), time.Date(2024, 4, 2, 9, 0, 0, 0, prague)},
+		{"0 9 2 * *", time.Date(2024, 4, 2, 8, 59, 30, 0, prague), time.Date(2024, 4, 2, 9, 0, 0, 0, prague)},
+		{"*/15 * * * *", time.Date(2024, 3, 15, 10, 7, 0, 0, prague), time.Date(2024, 3, 15, 10, 15, 0, 0, prague)},
+		{"30 8 * * 1-5", time.Date(2024, 3, 16, 0, 0, 0, 0, prague), time.Date(2024, 3, 18, 8, 30, 0, 0, prague)},
+		{"0 0 1 1 *", time.Date(2024, 6, 1, 0, 0, 0, 0, prague), time.Date(2025, 1, 1, 0, 0, 0, 0, prague)},
+		// Both day fields restricted: either one matches
+		{"0 12 1 * 0", time.Date(2024, 3, 2, 0, 0, 0, 0, prague), time.Date(2024, 3, 3, 12, 0, 0, 0, prague)},
+		// Day-of-week 7 is Sunday as well
+		{"0 12 * * 7", time.Date(2024, 3, 4, 0, 0, 0, 0, prague), time.Date(2024, 3, 10, 12, 0, 0, 0, prague)},
+		// 02:30 does not exist on the day clocks move forward
+		{"30 2 * * *", time.Date(2024, 3, 30, 12, 0, 0, 0, prague), time.Date(2024, 4, 1, 2, 30, 0, 0, prague)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseCronSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", tt.expr, err)
+		}
+		if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Expected %q after %s to be %s, got %s", tt.expr, tt.from, tt.want, got)
+		}
+	}
+}
+
+func TestParseCronScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "0 9 2 *", "60 * * * *", "0 9 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCronSchedule(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+const (
+	latestExportFileName = "attendance_export.xlsx"
+	// Sheets the latest export is read from
+	latestExportContextFileName = "attendance_export.json"
+)
+
+// DashboardService combines the attendance export with generated report
+// versions and submissions into a per-member overview of a period. The export
+// last uploaded by a coordinator is kept, in the data directory if one is
+// configured, so background jobs can work with it.
+type DashboardService struct {
+	excelService    *ExcelService
+	versionStore    *VersionStore
+	submissionStore *SubmissionStore
+	profileStore    *ProfileStore
+	dataDir         string
+	latestExport    []byte
+	latestContext   ParseContext
+	mutex           sync.RWMutex
+}
+
+func NewDashboardService(
+	excelService *ExcelService,
+	versionStore *VersionStore,
+	submissionStore *SubmissionStore,
+	profileStore *ProfileStore,
+	dataDir string,
+) *DashboardService {
+	return &DashboardService{
+		excelService:    excelService,
+		versionStore:    versionStore,
+		submissionStore: submissionStore,
+		profileStore:    profileStore,
+		dataDir:         dataDir,
+	}
+}
+
+// SaveExport remembers an uploaded attendance export as the latest one,
+// along with the sheets it is read from
+func (ds *DashboardService) SaveExport(fileData []byte, pc ParseContext) {
+	ds.mutex.Lock()
+	ds.latestExport = fileData
+	ds.latestContext = pc
+	ds.mutex.Unlock()
+
+	if ds.dataDir == "" {
+		return
+	}
+	if err := saveJSONFile(ds.dataDir, latestExportContextFileName, pc); err != nil {
+		log.Printf("Error saving attendance export sheets: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(ds.dataDir, latestExportFileName), fileData); err != nil {
+		log.Printf("Error saving attendance export: %v", err)
+	}
+}
+
+// LatestExport returns the attendance export last passed to SaveExport and
+// the sheets it is read from. The data directory is preferred, since another
+// replica may have received a newer upload.
+func (ds *DashboardService) LatestExport() ([]byte, ParseContext, bool) {
+	if ds.dataDir != "" {
+		data, err := os.ReadFile(filepath.Join(ds.dataDir, latestExportFileName))
+		if err == nil {
+			var pc ParseContext
+			if err := loadJSONFile(ds.dataDir, latestExportContextFileName, &pc); err != nil {
+				log.Printf("Error reading attendance export sheets: %v", err)
+			}
+			return data, pc, true
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error reading attendance export: %v", err)
+		}
+	}
+
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+	return ds.latestExport, ds.latestContext, ds.latestExport != nil
+}
+
+// BuildLatest builds the dashboard of a period from the latest export
+func (ds *DashboardService) BuildLatest(year, month int) ([]models.DashboardRow, error) {
+	fileData, pc, ok := ds.LatestExport()
+	if !ok {
+		return nil, errors.New("no attendance export has been uploaded yet")
+	}
+
+	files := []models.SourceFile{{Data: fileData}}
+	names, _, err := ds.excelService.ParseSourcesForNamesAndMonths(files, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	return ds.Build(fileData, pc, names, year, month)
+}
+
+// Build returns one row per member of the export for the given period, with
+// the team of the member's profile
+func (ds *DashboardService) Build(fileData []byte, pc ParseContext, names []string, year, month int) ([]models.DashboardRow, error) {
+	files := []models.SourceFile{{Data: fileData}}
+	tableData, err := ds.excelService.ExtractMembersTableData(files, pc, month, ExtractOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []models.DashboardRow
+	for _, name := range names {
+		profile, _ := ds.profileStore.Get(name)
+
+		// The export may span several years, keep only the requested one
+		var periodRows []models.TableRow
+		for _, row := range tableData[name] {
+			if date, err := time.Parse("2006-01-02", row.Date); err == nil && date.Year() == year {
+				periodRows = append(periodRows, row)
+			}
+		}
+
+		row := models.DashboardRow{
+			Name:   name,
+			Team:   profile.Team,
+			Events: len(periodRows),
+			Hours:  ComputeTotals(periodRows).TotalHours,
+			Status: models.DashboardMissing,
+		}
+
+		versions := ds.versionStore.List(name, year, month)
+		row.Versions = len(versions)
+		for _, v := range versions {
+			row.LastGenerated = v.CreatedAt
+			row.Emailed = row.Emailed || v.Emailed
+		}
+		if row.Versions > 0 {
+			row.Status = models.DashboardGenerated
+		}
+		if row.Emailed {
+			row.Status = models.DashboardEmailed
+		}
+
+		if submission, ok := ds.submissionStore.Latest(name, year, month); ok {
+			row.SubmissionStatus = submission.Status
+			row.Status = submission.Status
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// DashboardTeams returns the distinct teams of dashboard rows, sorted
+func DashboardTeams(rows []models.DashboardRow) []string {
+	var teams []string
+	for _, row := range rows {
+		if row.Team != "" && !slices.Contains(teams, row.Team) {
+			teams = append(teams, row.Team)
+		}
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+// FilterDashboard keeps the rows with the given status and team (all when
+// empty) whose member name contains search, ignoring case and diacritics
+func FilterDashboard(rows []models.DashboardRow, status, team, search string) []models.DashboardRow {
+	search = strings.ToLower(utils.RemoveDiacritics(strings.TrimSpace(search)))
+
+	var filtered []models.DashboardRow
+	for _, row := range rows {
+		if status != "" && row.Status != status {
+			continue
+		}
+		if team != "" && row.Team != team {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(utils.RemoveDiacritics(row.Name)), search) {
+			continue
+		}
+		filtered = append(filtered, row)
+	}
+	return filtered
+}
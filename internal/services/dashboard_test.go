@@ -0,0 +1,99 @@
+package services
+
+import (
+	"testing"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/testutil"
+)
+
+func TestDashboardBuild(t *testing.T) {
+	excelService := NewExcelService("", "docházka realizačního týmu")
+	versionStore := NewVersionStore("")
+	submissionStore := NewSubmissionStore("")
+	profileStore := NewProfileStore("")
+	profileStore.Save(models.MemberProfile{Member: "test user", Team: "U19"})
+	dashboardService := NewDashboardService(excelService, versionStore, submissionStore, profileStore, "")
+
+	fileData := testutil.CreateTestExcelFile(t)
+	names := []string{"Another User", "Test User", "Missing User"}
+
+	version := versionStore.Add("Test User", 2023, 1, nil, "test.xlsx")
+	versionStore.MarkEmailed(version.ID)
+	versionStore.Add("Another User", 2023, 1, nil, "another.xlsx")
+	submissionStore.Submit(versionStore.Add("Another User", 2023, 1, nil, "another.xlsx"), "")
+
+	rows, err := dashboardService.Build(fileData, ParseContext{}, names, 2023, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := []struct {
+		events   int
+		hours    float64
+		versions int
+		status   string
+	}{
+		{1, 1.5, 2, models.SubmissionPending},
+		{2, 4, 1, models.DashboardEmailed},
+		{0, 0, 0, models.DashboardMissing},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %d", len(want), len(rows))
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.Events != w.events || row.Hours != w.hours || row.Versions != w.versions || row.Status != w.status {
+			t.Errorf("Row %d (%s): got %+v, want %+v", i, names[i], row, w)
+		}
+	}
+
+	if rows[1].Team != "U19" || rows[0].Team != "" {
+		t.Errorf("Expected the team of the profile, got %q and %q", rows[0].Team, rows[1].Team)
+	}
+	if teams := DashboardTeams(rows); len(teams) != 1 || teams[0] != "U19" {
+		t.Errorf("Expected the team U19, got %v", teams)
+	}
+	if filtered := FilterDashboard(rows, "", "U19", ""); len(filtered) != 1 || filtered[0].Name != "Test User" {
+		t.Errorf("Expected the members of team U19, got %v", filtered)
+	}
+
+	// A different year of the same month has no events
+	rows, _ = dashboardService.Build(fileData, ParseContext{}, names, 2024, 1)
+	if rows[1].Events != 0 {
+		t.Errorf("Expected no events in 2024, got %d", rows[1].Events)
+	}
+
+	filtered := FilterDashboard([]models.DashboardRow{
+		{Name: "Novák Jan", Status: models.DashboardMissing},
+		{Name: "Dvořák Petr", Status: models.DashboardEmailed},
+	}, "", "", "novak")
+	if len(filtered) != 1 || filtered[0].Name != "Novák Jan" {
+		t.Errorf("Expected search to ignore diacritics, got %v", filtered)
+	}
+}
+
+func TestDashboardLatestExport(t *testing.T) {
+	dataDir := t.TempDir()
+	excelService := NewExcelService("", "docházka realizačního týmu")
+	dashboardService := NewDashboardService(excelService, NewVersionStore(""), NewSubmissionStore(""), nil, dataDir)
+
+	if _, _, ok := dashboardService.LatestExport(); ok {
+		t.Fatal("Expected no export before an upload")
+	}
+
+	fileData := testutil.CreateTestExcelFile(t)
+	dashboardService.SaveExport(fileData, ParseContext{Sheets: []string{"docházka realizačního týmu"}})
+
+	// Another replica reads the export along with its sheets
+	replica := NewDashboardService(excelService, NewVersionStore(""), NewSubmissionStore(""), nil, dataDir)
+	data, pc, ok := replica.LatestExport()
+	if !ok || len(data) != len(fileData) || len(pc.Sheets) != 1 || pc.Sheets[0] != "docházka realizačního týmu" {
+		t.Errorf("Expected the export and its sheets, got %d bytes and %+v", len(data), pc)
+	}
+
+	rows, err := replica.BuildLatest(2023, 1)
+	if err != nil || len(rows) != 2 {
+		t.Errorf("Expected the dashboard of both members, got %+v (%v)", rows, err)
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+// skippedFillColor highlights skipped rows in the annotated source workbook
+const skippedFillColor = "#F8D7DA"
+
+// AnnotationLabels are the texts written into the annotated source workbook
+type AnnotationLabels struct {
+	// Header of the column holding the reason a row was skipped
+	Header string
+	// Reasons by skip reason (ExcludedDeclined, ...), the code is used
+	// when missing
+	Reasons map[string]string
+}
+
+// Diagnose reports how the attendance exports of a session are read: the
+// sheet, the columns each field is taken from and the data rows no timesheet
+// takes an event from, with the reason. Unconfirmed attendance is reported as
+// skipped even though it can be included as tentative rows.
+func (es *ExcelService) Diagnose(files []models.SourceFile, pc ParseContext) (models.SourceDiagnostics, error) {
+	diagnostics := models.SourceDiagnostics{
+		Skipped: make(map[string]int),
+	}
+	for _, file := range files {
+		diagnostics.Files = append(diagnostics.Files, file.Filename)
+	}
+
+	rows, err := es.readSources(files, pc)
+	if err != nil {
+		return diagnostics, err
+	}
+
+	// Columns are mapped by position, the headers of the first sheet show
+	// what they hold
+	var header []string
+	sheetSet := make(map[string]bool)
+	for i, file := range files {
+		sheets, _, err := es.readSourceSheets(file.Data, es.sheetsFor(pc))
+		if err != nil {
+			return diagnostics, err
+		}
+		for _, sheet := range sheets {
+			if i == 0 && header == nil && len(sheet.rows) > 0 {
+				header = sheet.rows[0]
+			}
+			if !sheetSet[sheet.name] {
+				sheetSet[sheet.name] = true
+				diagnostics.Sheets = append(diagnostics.Sheets, sheet.name)
+			}
+		}
+	}
+
+	for _, f := range es.sourceFields() {
+		column, _ := excelize.ColumnNumberToName(f.index + 1)
+		diagnostics.Columns = append(diagnostics.Columns, models.SourceColumn{
+			Field:  f.field,
+			Column: column,
+			Header: strings.TrimSpace(utils.SafeGetCellValue(header, f.index)),
+		})
+	}
+
+	for _, row := range rows {
+		if isBlankRow(row.cells) {
+			continue
+		}
+		diagnostics.TotalRows++
+
+		reason := es.skipReason(row)
+		if reason == "" {
+			diagnostics.UsedRows++
+			continue
+		}
+		diagnostics.Skipped[reason]++
+		diagnostics.SkippedRows = append(diagnostics.SkippedRows, models.SkippedRow{
+			File:   files[row.file].Filename,
+			Sheet:  row.sheet,
+			Row:    row.number,
+			Member: utils.SafeGetCellValue(row.cells, es.idxClen),
+			Reason: reason,
+		})
+	}
+
+	return diagnostics, nil
+}
+
+// skipReason returns why no timesheet takes an event from a row, or an empty
+// string when the row is used
+func (es *ExcelService) skipReason(row sourceRow) string {
+	if strings.TrimSpace(utils.SafeGetCellValue(row.cells, es.idxClen)) == "" {
+		return ExcludedMissingMember
+	}
+	for _, index := range []int{es.idxDatum1, es.idxDatum2} {
+		if _, err := es.dateParser.Parse(utils.SafeGetCellValue(row.cells, index), row.date1904); err != nil {
+			return ExcludedInvalidDate
+		}
+	}
+	if row.duplicate {
+		return ExcludedDuplicate
+	}
+	if !es.eventTypes.Allows(strings.TrimSpace(utils.SafeGetCellValue(row.cells, es.idxTypUdalosti))) {
+		return ExcludedEventType
+	}
+	switch es.attendance.Status(utils.SafeGetCellValue(row.cells, es.idxAttended)) {
+	case AttendanceDeclined:
+		return ExcludedDeclined
+	case AttendanceUnconfirmed:
+		return ExcludedUnconfirmed
+	}
+	return ""
+}
+
+// AnnotateSource returns a copy of one of the attendance exports of a session
+// with the rows that Diagnose reports as skipped highlighted and their reason
+// written into a new column after the last column of their sheet
+func (es *ExcelService) AnnotateSource(files []models.SourceFile, pc ParseContext, file int, labels AnnotationLabels) ([]byte, error) {
+	if file < 0 || file >= len(files) {
+		return nil, fmt.Errorf("no uploaded file %d", file)
+	}
+
+	rows, err := es.readSources(files, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(files[file].Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer f.Close()
+
+	// Skipped cells keep their style, with the fill replaced
+	highlighted := make(map[int]int)
+	reasonColumns := make(map[string]string)
+	for _, row := range rows {
+		if row.file != file || isBlankRow(row.cells) {
+			continue
+		}
+		skipReason := es.skipReason(row)
+		if skipReason == "" {
+			continue
+		}
+
+		// The reason goes into a new column after the last one of the sheet
+		sheet := row.sheet
+		reasonColumn, ok := reasonColumns[sheet]
+		if !ok {
+			cols, err := f.GetCols(sheet)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get columns from sheet %s: %w", sheet, err)
+			}
+			if reasonColumn, err = excelize.ColumnNumberToName(len(cols) + 1); err != nil {
+				return nil, err
+			}
+			if err := f.SetCellValue(sheet, reasonColumn+"1", labels.Header); err != nil {
+				return nil, fmt.Errorf("failed to write reason header: %w", err)
+			}
+			reasonColumns[sheet] = reasonColumn
+		}
+		lastCol, _ := excelize.ColumnNameToNumber(reasonColumn)
+
+		for col := 1; col <= lastCol; col++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row.number)
+			styleID, err := f.GetCellStyle(sheet, cell)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get style at %s: %w", cell, err)
+			}
+			newID, ok := highlighted[styleID]
+			if !ok {
+				style, err := f.GetStyle(styleID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read style at %s: %w", cell, err)
+				}
+				style.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{skippedFillColor}}
+				if newID, err = f.NewStyle(style); err != nil {
+					return nil, fmt.Errorf("failed to create highlight style: %w", err)
+				}
+				highlighted[styleID] = newID
+			}
+			if err := f.SetCellStyle(sheet, cell, cell, newID); err != nil {
+				return nil, fmt.Errorf("failed to highlight %s: %w", cell, err)
+			}
+		}
+
+		reason := labels.Reasons[skipReason]
+		if reason == "" {
+			reason = skipReason
+		}
+		cell := fmt.Sprintf("%s%d", reasonColumn, row.number)
+		if err := f.SetCellValue(sheet, cell, reason); err != nil {
+			return nil, fmt.Errorf("failed to write reason at %s: %w", cell, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write annotated file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
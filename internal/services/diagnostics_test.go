@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+
+	"timesheet-filler/internal/models"
+)
+
+func createDiagnosticsTestFile(t *testing.T) []byte {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "docházka realizačního týmu"
+	f.NewSheet(sheet)
+	f.SetSheetRow(sheet, "A1", &[]string{"ID", "Člen", "", "", "", "", "Účast potvrzena", "", "Typ události", "Název události", "", "Od", "Do"})
+	f.SetSheetRow(sheet, "A2", &[]string{"1", "Test User", "", "", "", "", "ano", "", "trénink", "Practice", "", "2024-03-04 18:00", "2024-03-04 20:00"})
+	f.SetSheetRow(sheet, "A3", &[]string{"2", "", "", "", "", "", "ano", "", "trénink", "Practice", "", "2024-03-04 18:00", "2024-03-04 20:00"})
+	f.SetSheetRow(sheet, "A4", &[]string{"3", "Test User", "", "", "", "", "ano", "", "trénink", "Practice", "", "4. března", "2024-03-05 20:00"})
+	f.SetSheetRow(sheet, "A5", &[]string{"4", "Test User", "", "", "", "", "ano", "", "schůze", "Meeting", "", "2024-03-06 18:00", "2024-03-06 20:00"})
+	f.SetSheetRow(sheet, "A6", &[]string{"5", "Test User", "", "", "", "", "ne", "", "trénink", "Practice", "", "2024-03-07 18:00", "2024-03-07 20:00"})
+	f.SetSheetRow(sheet, "A7", &[]string{"6", "Test User", "", "", "", "", "", "", "trénink", "Practice", "", "2024-03-08 18:00", "2024-03-08 20:00"})
+	// Blank rows are not counted
+	f.SetSheetRow(sheet, "A9", &[]string{"7", "Another User", "", "", "", "", "ano", "", "zápas", "Game", "", "2024-03-09 10:00", "2024-03-09 12:00"})
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDiagnose(t *testing.T) {
+	excelService := NewExcelService("", "docházka realizačního týmu", WithEventTypeFilter(EventTypeFilter{Exclude: []string{"schůze"}}))
+
+	diagnostics, err := excelService.Diagnose([]models.SourceFile{{Data: createDiagnosticsTestFile(t)}}, ParseContext{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if diagnostics.TotalRows != 7 || diagnostics.UsedRows != 2 {
+		t.Errorf("Expected 7 rows with 2 used, got %d with %d used", diagnostics.TotalRows, diagnostics.UsedRows)
+	}
+	if len(diagnostics.Columns) != 6 || diagnostics.Columns[0].Column != "B" || diagnostics.Columns[0].Header != "Člen" {
+		t.Errorf("Expected the member to be mapped to column B, got %+v", diagnostics.Columns)
+	}
+
+	wantRows := map[int]string{
+		3: ExcludedMissingMember,
+		4: ExcludedInvalidDate,
+		5: ExcludedEventType,
+		6: ExcludedDeclined,
+		7: ExcludedUnconfirmed,
+	}
+	if len(diagnostics.SkippedRows) != len(wantRows) {
+		t.Fatalf("Expected %d skipped rows, got %+v", len(wantRows), diagnostics.SkippedRows)
+	}
+	for _, skipped := range diagnostics.SkippedRows {
+		if wantRows[skipped.Row] != skipped.Reason {
+			t.Errorf("Expected row %d to be skipped as %s, got %s", skipped.Row, wantRows[skipped.Row], skipped.Reason)
+		}
+		if diagnostics.Skipped[skipped.Reason] != 1 {
+			t.Errorf("Expected 1 row skipped as %s, got %d", skipped.Reason, diagnostics.Skipped[skipped.Reason])
+		}
+	}
+}
+
+func TestAnnotateSource(t *testing.T) {
+	excelService := NewExcelService("", "docházka realizačního týmu")
+
+	annotated, err := excelService.AnnotateSource([]models.SourceFile{{Data: createDiagnosticsTestFile(t)}}, ParseContext{}, 0, AnnotationLabels{
+		Header:  "Reason",
+		Reasons: map[string]string{ExcludedDeclined: "Declined"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(annotated))
+	if err != nil {
+		t.Fatalf("Failed to open annotated file: %v", err)
+	}
+	defer f.Close()
+
+	sheet := "docházka realizačního týmu"
+	tests := map[string]string{
+		"N1": "Reason",
+		"N2": "",
+		"N6": "Declined",
+		"N3": ExcludedMissingMember, // No label, the code is used
+	}
+	for cell, want := range tests {
+		if got, _ := f.GetCellValue(sheet, cell); got != want {
+			t.Errorf("Expected %s to be %q, got %q", cell, want, got)
+		}
+	}
+
+	for cell, highlighted := range map[string]bool{"B6": true, "M6": true, "B2": false} {
+		styleID, _ := f.GetCellStyle(sheet, cell)
+		style, err := f.GetStyle(styleID)
+		if err != nil {
+			t.Fatalf("Failed to read style of %s: %v", cell, err)
+		}
+		got := len(style.Fill.Color) > 0 && "#"+style.Fill.Color[0] == skippedFillColor
+		if got != highlighted {
+			t.Errorf("Expected %s highlighted=%t, got %t", cell, highlighted, got)
+		}
+	}
+}
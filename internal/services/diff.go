@@ -0,0 +1,79 @@
+package services
+
+import (
+	"sort"
+
+	"timesheet-filler/internal/models"
+)
+
+// Kinds of row differences between two report versions
+const (
+	DiffUnchanged = "unchanged"
+	DiffAdded     = "added"
+	DiffRemoved   = "removed"
+	DiffChanged   = "changed"
+)
+
+// DiffRows compares the rows of two report versions. Rows are matched by date
+// and start time; a matched row whose end time or note differs is reported
+// as changed. The result is ordered by date and start time.
+func DiffRows(from, to []models.TableRow) []models.RowDiff {
+	rowKey := func(row models.TableRow) string {
+		return row.Date + " " + row.StartTime
+	}
+
+	unmatched := make(map[string][]int)
+	for i, row := range from {
+		unmatched[rowKey(row)] = append(unmatched[rowKey(row)], i)
+	}
+
+	matched := make([]bool, len(from))
+	var diff []models.RowDiff
+	for _, row := range to {
+		candidates := unmatched[rowKey(row)]
+		if len(candidates) == 0 {
+			diff = append(diff, models.RowDiff{Kind: DiffAdded, New: row})
+			continue
+		}
+
+		// Prefer an identical row when the same start appears more than once
+		pick := 0
+		for j, idx := range candidates {
+			if from[idx] == row {
+				pick = j
+				break
+			}
+		}
+		idx := candidates[pick]
+		unmatched[rowKey(row)] = append(candidates[:pick:pick], candidates[pick+1:]...)
+		matched[idx] = true
+
+		kind := DiffUnchanged
+		if from[idx] != row {
+			kind = DiffChanged
+		}
+		diff = append(diff, models.RowDiff{Kind: kind, Old: from[idx], New: row})
+	}
+
+	for i, row := range from {
+		if !matched[i] {
+			diff = append(diff, models.RowDiff{Kind: DiffRemoved, Old: row})
+		}
+	}
+
+	diffRow := func(d models.RowDiff) models.TableRow {
+		if d.Kind == DiffRemoved {
+			return d.Old
+		}
+		return d.New
+	}
+	sort.SliceStable(diff, func(i, j int) bool {
+		a, b := diffRow(diff[i]), diffRow(diff[j])
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.StartTime < b.StartTime
+	})
+
+	return diff
+}
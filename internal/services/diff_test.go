@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"timesheet-filler/internal/models"
+)
+
+func TestDiffRows(t *testing.T) {
+	from := []models.TableRow{
+		{Date: "2024-03-02", StartTime: "10:00", EndTime: "12:00", Note: "Practice"},
+		{Date: "2024-03-05", StartTime: "18:00", EndTime: "20:00", Note: "Game"},
+		{Date: "2024-03-09", StartTime: "09:00", EndTime: "11:00", Note: "Camp"},
+	}
+	to := []models.TableRow{
+		{Date: "2024-03-02", StartTime: "10:00", EndTime: "12:00", Note: "Practice"},
+		{Date: "2024-03-05", StartTime: "18:00", EndTime: "21:00", Note: "Game"},
+		{Date: "2024-03-07", StartTime: "17:00", EndTime: "19:00", Note: "Meeting"},
+	}
+
+	got := DiffRows(from, to)
+
+	want := []string{DiffUnchanged, DiffChanged, DiffAdded, DiffRemoved}
+	if len(got) != len(want) {
+		t.Fatalf("DiffRows() returned %d rows (%v), want %d", len(got), got, len(want))
+	}
+	for i, kind := range want {
+		if got[i].Kind != kind {
+			t.Errorf("row %d: got kind %q, want %q", i, got[i].Kind, kind)
+		}
+	}
+
+	if got[1].Old.EndTime != "20:00" || got[1].New.EndTime != "21:00" {
+		t.Errorf("changed row: got %+v", got[1])
+	}
+}
+
+func TestVersionStore(t *testing.T) {
+	dataDir := t.TempDir()
+	versionStore := NewVersionStore(dataDir)
+
+	rows := []models.TableRow{
+		{Date: "2024-03-02", StartTime: "10:00", EndTime: "12:00", Note: "Practice"},
+	}
+
+	first := versionStore.Add("Novák Jan", 2024, 3, rows, "first.xlsx")
+	second := versionStore.Add("Novák Jan", 2024, 3, rows, "second.xlsx")
+	other := versionStore.Add("Novák Jan", 2024, 4, rows, "other.xlsx")
+
+	if first.Number != 1 || second.Number != 2 || other.Number != 1 {
+		t.Errorf("Expected version numbers 1, 2 and 1, got %d, %d and %d", first.Number, second.Number, other.Number)
+	}
+	if first.TotalHours != 2 {
+		t.Errorf("Expected total hours 2, got %v", first.TotalHours)
+	}
+
+	if !versionStore.MarkEmailed(second.ID) {
+		t.Fatal("Expected MarkEmailed to find the version")
+	}
+
+	// Versions are reloaded from the data directory
+	versions := NewVersionStore(dataDir).List("Novák Jan", 2024, 3)
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(versions))
+	}
+	if versions[0].ID != first.ID || versions[1].ID != second.ID {
+		t.Errorf("Expected versions in generation order")
+	}
+	if versions[0].Emailed || !versions[1].Emailed {
+		t.Errorf("Expected only the second version to be emailed")
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"timesheet-filler/internal/models"
+)
+
+const draftsFileName = "drafts.json"
+
+// DraftStore keeps unfinished edits keyed by client, member, year and month. When
+// a data directory is configured, drafts are also written to disk so they
+// survive restarts.
+type DraftStore struct {
+	drafts          map[string]models.Draft
+	mutex           sync.RWMutex
+	snapshot        *jsonSnapshotStore[string, models.Draft]
+	expiryTime      time.Duration
+	cleanupInterval time.Duration
+}
+
+func NewDraftStore(expiryTime time.Duration, cleanupInterval time.Duration, dataDir string) *DraftStore {
+	if cleanupInterval == 0 {
+		cleanupInterval = time.Hour
+	}
+	ds := &DraftStore{
+		drafts:          make(map[string]models.Draft),
+		expiryTime:      expiryTime,
+		cleanupInterval: cleanupInterval,
+		snapshot:        newJSONSnapshotStore[string, models.Draft](dataDir, draftsFileName, "drafts"),
+	}
+
+	if err := ds.load(); err != nil {
+		log.Printf("Error loading drafts: %v", err)
+	}
+
+	// Start cleanup goroutine
+	go ds.cleanupRoutine()
+
+	return ds
+}
+
+func draftKey(clientID, name string, year int, month string) string {
+	return clientID + "\x00" + name + "\x00" + strconv.Itoa(year) + "\x00" + month
+}
+
+// Save stores or replaces the draft of a member and period for a client and
+// returns it with the update time set
+func (ds *DraftStore) Save(draft models.Draft) models.Draft {
+	draft.UpdatedAt = time.Now()
+
+	ds.mutex.Lock()
+	ds.drafts[draftKey(draft.ClientID, draft.Name, draft.Year, draft.Month)] = draft
+	ds.mutex.Unlock()
+
+	ds.persist()
+
+	return draft
+}
+
+func (ds *DraftStore) Get(clientID, name string, year int, month string) (models.Draft, bool) {
+	ds.mutex.RLock()
+	draft, ok := ds.drafts[draftKey(clientID, name, year, month)]
+	ds.mutex.RUnlock()
+
+	if ok && ds.expiryTime > 0 && time.Since(draft.UpdatedAt) > ds.expiryTime {
+		return models.Draft{}, false
+	}
+	return draft, ok
+}
+
+func (ds *DraftStore) Delete(clientID, name string, year int, month string) {
+	ds.mutex.Lock()
+	delete(ds.drafts, draftKey(clientID, name, year, month))
+	ds.mutex.Unlock()
+
+	ds.persist()
+}
+
+// List returns the drafts of a client, most recently updated first
+func (ds *DraftStore) List(clientID string) []models.Draft {
+	ds.mutex.RLock()
+	var drafts []models.Draft
+	for _, draft := range ds.drafts {
+		if draft.ClientID == clientID && (ds.expiryTime <= 0 || time.Since(draft.UpdatedAt) <= ds.expiryTime) {
+			drafts = append(drafts, draft)
+		}
+	}
+	ds.mutex.RUnlock()
+
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt)
+	})
+	return drafts
+}
+
+func (ds *DraftStore) cleanupRoutine() {
+	ticker := time.NewTicker(ds.cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ds.CleanupExpired()
+	}
+}
+
+func (ds *DraftStore) CleanupExpired() {
+	if ds.expiryTime <= 0 {
+		return
+	}
+
+	now := time.Now()
+	removed := false
+
+	ds.mutex.Lock()
+	for key, draft := range ds.drafts {
+		if now.Sub(draft.UpdatedAt) > ds.expiryTime {
+			delete(ds.drafts, key)
+			removed = true
+		}
+	}
+	ds.mutex.Unlock()
+
+	if removed {
+		ds.persist()
+	}
+}
+
+// load reads previously persisted drafts from the data directory
+func (ds *DraftStore) load() error {
+	drafts, err := ds.snapshot.load()
+	if err != nil {
+		return err
+	}
+
+	ds.mutex.Lock()
+	for _, draft := range drafts {
+		ds.drafts[draftKey(draft.ClientID, draft.Name, draft.Year, draft.Month)] = draft
+	}
+	ds.mutex.Unlock()
+
+	return nil
+}
+
+// persist writes all drafts to the data directory
+func (ds *DraftStore) persist() {
+	ds.snapshot.save(&ds.mutex, ds.drafts)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"timesheet-filler/internal/models"
+)
+
+func TestDraftStore(t *testing.T) {
+	dataDir := t.TempDir()
+	draftStore := NewDraftStore(time.Hour, time.Hour, dataDir)
+
+	rows := []models.TableRow{
+		{Date: "2024-03-09", StartTime: "18:00", EndTime: "20:00", Note: "Practice"},
+	}
+
+	saved := draftStore.Save(models.Draft{
+		ClientID:  "client-1",
+		Name:      "Jan Novák",
+		Year:      2024,
+		Month:     "3",
+		FileToken: "token",
+		TableData: rows,
+	})
+	if saved.UpdatedAt.IsZero() {
+		t.Error("Expected Save to set the update time")
+	}
+
+	// Drafts are isolated per client and year
+	if _, ok := draftStore.Get("client-2", "Jan Novák", 2024, "3"); ok {
+		t.Error("Expected no draft for another client")
+	}
+	if _, ok := draftStore.Get("client-1", "Jan Novák", 2025, "3"); ok {
+		t.Error("Expected no draft for the same month of another year")
+	}
+
+	draft, ok := draftStore.Get("client-1", "Jan Novák", 2024, "3")
+	if !ok {
+		t.Fatal("Failed to retrieve saved draft")
+	}
+	if len(draft.TableData) != 1 || draft.TableData[0] != rows[0] {
+		t.Errorf("Expected rows %v, got %v", rows, draft.TableData)
+	}
+
+	// Drafts are reloaded from the data directory
+	reloaded := NewDraftStore(time.Hour, time.Hour, dataDir)
+	if drafts := reloaded.List("client-1"); len(drafts) != 1 {
+		t.Fatalf("Expected 1 persisted draft, got %d", len(drafts))
+	}
+
+	draftStore.Delete("client-1", "Jan Novák", 2024, "3")
+	if _, ok := draftStore.Get("client-1", "Jan Novák", 2024, "3"); ok {
+		t.Error("Expected draft to be deleted")
+	}
+
+	reloaded = NewDraftStore(time.Hour, time.Hour, dataDir)
+	if drafts := reloaded.List("client-1"); len(drafts) != 0 {
+		t.Errorf("Expected deleted draft to be removed from disk, got %d drafts", len(drafts))
+	}
+}
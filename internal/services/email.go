@@ -142,10 +142,39 @@ func NewEmailService(
 	}
 }
 
+// WithRouting returns a copy of the service sending from another address
+// and to other default recipients. Empty values keep those of s.
+func (s *EmailService) WithRouting(fromEmail, fromName string, defaultTos []string) *EmailService {
+	routed := *s
+	if fromEmail != "" {
+		routed.FromEmail = fromEmail
+	}
+	if fromName != "" {
+		routed.FromName = fromName
+	}
+	if len(defaultTos) > 0 {
+		routed.DefaultTos = defaultTos
+	}
+	return &routed
+}
+
 func (s *EmailService) SendEmailWithAttachment(
 	subject, body string,
 	to, cc []string,
 	attachment *EmailAttachment,
+) error {
+	var attachments []*EmailAttachment
+	if attachment != nil {
+		attachments = append(attachments, attachment)
+	}
+	return s.SendEmailWithAttachments(subject, body, to, cc, attachments)
+}
+
+// SendEmailWithAttachments sends an email with any number of attachments
+func (s *EmailService) SendEmailWithAttachments(
+	subject, body string,
+	to, cc []string,
+	attachments []*EmailAttachment,
 ) error {
 	if !s.IsInitialized {
 		return fmt.Errorf("email service not properly initialized")
@@ -153,15 +182,15 @@ func (s *EmailService) SendEmailWithAttachment(
 
 	switch s.Provider {
 	case ProviderSendGrid:
-		return s.sendWithSendGrid(to, cc, subject, body, attachment)
+		return s.sendWithSendGrid(to, cc, subject, body, attachments)
 	case ProviderAWSSES:
-		return s.sendWithAWSSES(to, cc, subject, body, attachment)
+		return s.sendWithAWSSES(to, cc, subject, body, attachments)
 	case ProviderOCIEmail:
-		return s.sendWithOCIEmail(to, cc, subject, body, attachment)
+		return s.sendWithOCIEmail(to, cc, subject, body, attachments)
 	case ProviderMailJet:
-		return s.sendWithMailJet(to, cc, subject, body, attachment)
+		return s.sendWithMailJet(to, cc, subject, body, attachments)
 	case ProviderResend:
-		return s.sendWithResend(to, cc, subject, body, attachment)
+		return s.sendWithResend(to, cc, subject, body, attachments)
 	default:
 		return fmt.Errorf("unknown email provider: %s", s.Provider)
 	}
@@ -172,7 +201,7 @@ func (s *EmailService) sendWithSendGrid(
 	cc []string,
 	subject string,
 	body string,
-	attachment *EmailAttachment,
+	attachments []*EmailAttachment,
 ) error {
 	from := mail.NewEmail(s.FromName, s.FromEmail)
 
@@ -199,8 +228,8 @@ func (s *EmailService) sendWithSendGrid(
 	content := mail.NewContent("text/html", body)
 	m.AddContent(content)
 
-	// Add attachment if provided
-	if attachment != nil {
+	// Add attachments if provided
+	for _, attachment := range attachments {
 		a := mail.NewAttachment()
 		a.SetFilename(attachment.FileName)
 		a.SetType(attachment.ContentType)
@@ -233,7 +262,7 @@ func (s *EmailService) sendWithAWSSES(
 	cc []string,
 	subject string,
 	body string,
-	attachment *EmailAttachment,
+	attachments []*EmailAttachment,
 ) error {
 	// Create AWS session
 	sess, err := session.NewSession(&aws.Config{
@@ -256,9 +285,9 @@ func (s *EmailService) sendWithAWSSES(
 		destinations = append(destinations, aws.String(recipient))
 	}
 
-	// If we have an attachment, we need to send raw email
-	if attachment != nil {
-		return s.sendRawEmailWithSES(svc, to, cc, subject, body, attachment)
+	// If we have attachments, we need to send raw email
+	if len(attachments) > 0 {
+		return s.sendRawEmailWithSES(svc, to, cc, subject, body, attachments)
 	}
 
 	// Simple email without attachment
@@ -291,7 +320,7 @@ func (s *EmailService) sendWithAWSSES(
 	return nil
 }
 
-func (s *EmailService) sendRawEmailWithSES(svc *ses.SES, to, cc []string, subject, body string, attachment *EmailAttachment) error {
+func (s *EmailService) sendRawEmailWithSES(svc *ses.SES, to, cc []string, subject, body string, attachments []*EmailAttachment) error {
 	// Build raw email message
 	var buffer bytes.Buffer
 
@@ -313,15 +342,17 @@ func (s *EmailService) sendRawEmailWithSES(svc *ses.SES, to, cc []string, subjec
 	buffer.WriteString(body)
 	buffer.WriteString("\r\n")
 
-	// Attachment
-	buffer.WriteString("--boundary123\r\n")
-	buffer.WriteString(fmt.Sprintf("Content-Type: %s\r\n", attachment.ContentType))
-	buffer.WriteString("Content-Transfer-Encoding: base64\r\n")
-	buffer.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", attachment.FileName))
-	buffer.WriteString("\r\n")
-	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
-	buffer.WriteString(encoded)
-	buffer.WriteString("\r\n")
+	// Attachments
+	for _, attachment := range attachments {
+		buffer.WriteString("--boundary123\r\n")
+		buffer.WriteString(fmt.Sprintf("Content-Type: %s\r\n", attachment.ContentType))
+		buffer.WriteString("Content-Transfer-Encoding: base64\r\n")
+		buffer.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", attachment.FileName))
+		buffer.WriteString("\r\n")
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		buffer.WriteString(encoded)
+		buffer.WriteString("\r\n")
+	}
 	buffer.WriteString("--boundary123--\r\n")
 
 	// Prepare destinations
@@ -354,7 +385,7 @@ func (s *EmailService) sendWithOCIEmail(
 	cc []string,
 	subject string,
 	body string,
-	attachment *EmailAttachment,
+	attachments []*EmailAttachment,
 ) error {
 	client, err := emaildataplane.NewEmailDPClientWithConfigurationProvider(s.OCIConfigProvider)
 	if err != nil {
@@ -396,7 +427,7 @@ func (s *EmailService) sendWithOCIEmail(
 
 	// Add attachment handling here if needed - OCI email attachments are complex
 	// For now, we'll send without attachments or implement raw email
-	if attachment != nil {
+	if len(attachments) > 0 {
 		log.Printf("Warning: OCI Email attachments not fully implemented in this version")
 	}
 
@@ -421,7 +452,7 @@ func (s *EmailService) sendWithMailJet(
 	cc []string,
 	subject string,
 	body string,
-	attachment *EmailAttachment,
+	attachments []*EmailAttachment,
 ) error {
 	mj := mailjet.NewMailjetClient(s.MailJetAPIKey, s.MailJetSecretKey)
 
@@ -453,16 +484,18 @@ func (s *EmailService) sendWithMailJet(
 		HTMLPart: body,
 	}
 
-	// Add attachment if provided
-	if attachment != nil {
-		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
-		message.Attachments = &mailjet.AttachmentsV31{
-			{
+	// Add attachments if provided
+	if len(attachments) > 0 {
+		var mjAttachments mailjet.AttachmentsV31
+		for _, attachment := range attachments {
+			encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+			mjAttachments = append(mjAttachments, mailjet.AttachmentV31{
 				ContentType:   attachment.ContentType,
 				Filename:      attachment.FileName,
 				Base64Content: encoded,
-			},
+			})
 		}
+		message.Attachments = &mjAttachments
 	}
 
 	messages := mailjet.MessagesV31{Info: []mailjet.InfoMessagesV31{message}}
@@ -481,7 +514,7 @@ func (s *EmailService) sendWithResend(
 	cc []string,
 	subject string,
 	body string,
-	attachment *EmailAttachment,
+	attachments []*EmailAttachment,
 ) error {
 	client := resend.NewClient(s.ResendAPIKey)
 
@@ -498,14 +531,12 @@ func (s *EmailService) sendWithResend(
 		params.Cc = cc
 	}
 
-	// Add attachment if provided
-	if attachment != nil {
-		params.Attachments = []*resend.Attachment{
-			{
-				Content:  attachment.Data,
-				Filename: attachment.FileName,
-			},
-		}
+	// Add attachments if provided
+	for _, attachment := range attachments {
+		params.Attachments = append(params.Attachments, &resend.Attachment{
+			Content:  attachment.Data,
+			Filename: attachment.FileName,
+		})
 	}
 
 	sent, err := client.Emails.Send(params)
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"time"
+
+	"timesheet-filler/internal/models"
+)
+
+// EventSplitOptions controls how events crossing midnight are turned into
+// per-day timesheet rows.
+type EventSplitOptions struct {
+	// DailyCap limits the hours counted per day for events spanning at least
+	// one whole calendar day, so overnight events keep their hours. Zero
+	// disables the cap.
+	DailyCap time.Duration
+	// DayStart is the time of day at which a capped day begins when the event
+	// already runs from midnight, e.g. the middle days of a weekend camp.
+	DayStart time.Duration
+}
+
+// SplitEvent turns an event into one row per calendar day it covers. A segment
+// that runs until midnight gets an end time of 00:00, which the report
+// template interprets as 24:00.
+func SplitEvent(start, end time.Time, note string, opts EventSplitOptions) []models.TableRow {
+	if !end.After(start) {
+		return []models.TableRow{newEventRow(start, end, note)}
+	}
+
+	firstDay := truncateToDay(start)
+	firstWholeDay := firstDay
+	if !start.Equal(firstDay) {
+		firstWholeDay = firstDay.AddDate(0, 0, 1)
+	}
+	multiDay := !end.Before(firstWholeDay.AddDate(0, 0, 1))
+
+	var rows []models.TableRow
+	for day := firstDay; day.Before(end); day = day.AddDate(0, 0, 1) {
+		segStart := maxTime(start, day)
+		segEnd := minTime(end, day.AddDate(0, 0, 1))
+		if !segEnd.After(segStart) {
+			continue
+		}
+
+		if multiDay && opts.DailyCap > 0 && segEnd.Sub(segStart) > opts.DailyCap {
+			if segStart.Equal(day) && opts.DayStart > 0 && day.Add(opts.DayStart).Before(segEnd) {
+				segStart = day.Add(opts.DayStart)
+			}
+			segEnd = minTime(segEnd, segStart.Add(opts.DailyCap))
+		}
+
+		rows = append(rows, newEventRow(segStart, segEnd, note))
+	}
+
+	return rows
+}
+
+// SplitOvernightRows splits edited rows whose end time is before their start
+// time into rows for each day the entry covers.
+func SplitOvernightRows(tableData []models.TableRow, opts EventSplitOptions) []models.TableRow {
+	var result []models.TableRow
+	for _, row := range tableData {
+		date, err := time.Parse("2006-01-02", row.Date)
+		if err != nil {
+			result = append(result, row)
+			continue
+		}
+
+		start, startErr := time.Parse("15:04", row.StartTime)
+		end, endErr := time.Parse("15:04", row.EndTime)
+		if startErr != nil || endErr != nil || !end.Before(start) || isMidnight(end) {
+			result = append(result, row)
+			continue
+		}
+
+		startAt := date.Add(time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute)
+		endAt := date.AddDate(0, 0, 1).Add(time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute)
+		for _, dayRow := range SplitEvent(startAt, endAt, row.Note, opts) {
+			dayRow.EventType = row.EventType
+			dayRow.Origin = row.Origin
+			dayRow.Tentative = row.Tentative
+			result = append(result, dayRow)
+		}
+	}
+
+	return result
+}
+
+func newEventRow(start, end time.Time, note string) models.TableRow {
+	return models.TableRow{
+		Date:      start.Format("2006-01-02"),
+		StartTime: start.Format("15:04"),
+		EndTime:   end.Format("15:04"),
+		Note:      note,
+	}
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"timesheet-filler/internal/models"
+)
+
+func TestSplitEvent(t *testing.T) {
+	parse := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("invalid test time %q: %v", s, err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		opts  EventSplitOptions
+		want  []models.TableRow
+	}{
+		{
+			name:  "same day",
+			start: "2024-03-09 18:00",
+			end:   "2024-03-09 20:00",
+			want: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "18:00", EndTime: "20:00"},
+			},
+		},
+		{
+			name:  "overnight",
+			start: "2024-03-09 18:00",
+			end:   "2024-03-10 02:00",
+			want: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "18:00", EndTime: "00:00"},
+				{Date: "2024-03-10", StartTime: "00:00", EndTime: "02:00"},
+			},
+		},
+		{
+			name:  "ends at midnight",
+			start: "2024-03-09 18:00",
+			end:   "2024-03-10 00:00",
+			want: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "18:00", EndTime: "00:00"},
+			},
+		},
+		{
+			name:  "overnight with daily cap",
+			start: "2024-03-09 14:00",
+			end:   "2024-03-10 06:00",
+			opts:  EventSplitOptions{DailyCap: 8 * time.Hour, DayStart: 8 * time.Hour},
+			want: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "14:00", EndTime: "00:00"},
+				{Date: "2024-03-10", StartTime: "00:00", EndTime: "06:00"},
+			},
+		},
+		{
+			name:  "weekend camp with daily cap",
+			start: "2024-03-08 17:00",
+			end:   "2024-03-10 14:00",
+			opts:  EventSplitOptions{DailyCap: 8 * time.Hour, DayStart: 8 * time.Hour},
+			want: []models.TableRow{
+				{Date: "2024-03-08", StartTime: "17:00", EndTime: "00:00"},
+				{Date: "2024-03-09", StartTime: "08:00", EndTime: "16:00"},
+				{Date: "2024-03-10", StartTime: "08:00", EndTime: "14:00"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitEvent(parse(tt.start), parse(tt.end), "", tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SplitEvent() returned %d rows (%v), want %d", len(got), got, len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("row %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitOvernightRows(t *testing.T) {
+	rows := []models.TableRow{
+		{Date: "2024-03-09", StartTime: "18:00", EndTime: "02:00", Note: "Tournament", Tentative: true},
+		{Date: "2024-03-11", StartTime: "18:00", EndTime: "00:00", Note: "Practice"},
+	}
+
+	got := SplitOvernightRows(rows, EventSplitOptions{})
+
+	want := []models.TableRow{
+		{Date: "2024-03-09", StartTime: "18:00", EndTime: "00:00", Note: "Tournament", Tentative: true},
+		{Date: "2024-03-10", StartTime: "00:00", EndTime: "02:00", Note: "Tournament", Tentative: true},
+		{Date: "2024-03-11", StartTime: "18:00", EndTime: "00:00", Note: "Practice"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("SplitOvernightRows() returned %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
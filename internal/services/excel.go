@@ -2,18 +2,51 @@ package services
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/xuri/excelize/v2"
 
+	"timesheet-filler/internal/holidays"
 	metrics "timesheet-filler/internal/metrics"
 	"timesheet-filler/internal/models"
 	"timesheet-filler/internal/utils"
 )
 
+// MaxReportRows is the number of entry rows available in the report template
+const MaxReportRows = 31
+
+// DefaultReportMapping returns the cell layout of the bundled report template
+func DefaultReportMapping() models.ReportMapping {
+	return models.ReportMapping{
+		TargetSheet:     "výkaz práce",
+		FirstNameCell:   "B3",
+		LastNameCell:    "B4",
+		StartRow:        7,
+		MaxRows:         MaxReportRows,
+		DateColumn:      "A",
+		StartTimeColumn: "B",
+		EndTimeColumn:   "C",
+		NoteColumn:      "F",
+		HoursColumn:     "H",
+		TotalHoursCell:  "H39",
+		TimeStyleID:     25,
+		HolidayColumn:   "E",
+		DayOffFillColor: "#E7E6E6",
+
+		ApprovedByCell:      "C48",
+		ApprovedAtCell:      "C49",
+		ApprovedByLabelCell: "A48",
+		ApprovedAtLabelCell: "A49",
+	}
+}
+
 type SheetNotFoundError struct {
 	SheetName       string
 	AvailableSheets []string
@@ -24,34 +57,166 @@ func (e SheetNotFoundError) Error() string {
 }
 
 func IsSheetNotFoundError(err error) (SheetNotFoundError, bool) {
-	snfErr, ok := err.(SheetNotFoundError)
+	var snfErr SheetNotFoundError
+	ok := errors.As(err, &snfErr)
 	return snfErr, ok
 }
 
 type ExcelService struct {
 	templatePath     string
 	sourceSheet      string
-	targetSheet      string
+	mapping          models.ReportMapping
 	idxClen          int
 	idxAttended      int
 	idxTypUdalosti   int
 	idxNazevUdalosti int
 	idxDatum1        int
 	idxDatum2        int
+	splitOptions     EventSplitOptions
+	holidays         *holidays.Calendar
+	eventTypes       EventTypeFilter
+	attendance       AttendanceRules
+	dateParser       *utils.DateParser
+	reportTemplates  *ReportTemplateStore
+	profiles         *ProfileStore
+}
+
+// ExtractOptions narrows down the events taken from an attendance export
+type ExtractOptions struct {
+	// Selected event types, in addition to the configured filter. No
+	// selection keeps every allowed type.
+	EventTypes []string
+	// Keep events whose attendance is unconfirmed, marked as tentative
+	IncludeTentative bool
+}
+
+// Reasons why events of the attendance export were left out
+const (
+	ExcludedDeclined    = "declined"
+	ExcludedUnconfirmed = "unconfirmed"
+	ExcludedEventType   = "event_type"
+	ExcludedInvalidDate = "invalid_date"
+	// Rows without a member, only reported by Diagnose
+	ExcludedMissingMember = "missing_member"
+	// Rows repeating an event of an earlier row or export, only reported by
+	// Diagnose
+	ExcludedDuplicate = "duplicate"
+)
+
+// EventTypeFilter selects source events by their type. Types are compared
+// ignoring case and diacritics. An empty Include list allows every type not
+// listed in Exclude.
+type EventTypeFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Allows reports whether events of the given type are kept
+func (f EventTypeFilter) Allows(eventType string) bool {
+	if containsEventType(f.Exclude, eventType) {
+		return false
+	}
+	return len(f.Include) == 0 || containsEventType(f.Include, eventType)
+}
+
+// containsEventType reports whether types holds eventType, ignoring case and
+// diacritics
+func containsEventType(types []string, eventType string) bool {
+	normalized := normalizeEventType(eventType)
+	for _, t := range types {
+		if normalizeEventType(t) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeEventType(eventType string) string {
+	return strings.ToLower(utils.RemoveDiacritics(strings.TrimSpace(eventType)))
+}
+
+// ExcelOption customizes an ExcelService created by NewExcelService
+type ExcelOption func(*ExcelService)
+
+// WithEventSplitOptions sets how events crossing midnight are split into rows
+func WithEventSplitOptions(opts EventSplitOptions) ExcelOption {
+	return func(es *ExcelService) {
+		es.splitOptions = opts
+	}
+}
+
+// WithEventTypeFilter limits the source events used to the allowed types
+func WithEventTypeFilter(filter EventTypeFilter) ExcelOption {
+	return func(es *ExcelService) {
+		es.eventTypes = filter
+	}
+}
+
+// WithDateLayouts adds Go time layouts for reading dates of the attendance
+// export, tried before the built-in ones
+func WithDateLayouts(layouts []string) ExcelOption {
+	return func(es *ExcelService) {
+		es.dateParser = utils.NewDateParser(layouts...)
+	}
+}
+
+// WithAttendanceRules sets which attendance values count as attended
+func WithAttendanceRules(rules AttendanceRules) ExcelOption {
+	return func(es *ExcelService) {
+		es.attendance = rules
+	}
+}
+
+// WithHolidayCalendar marks public holidays and weekends in generated reports
+func WithHolidayCalendar(calendar *holidays.Calendar) ExcelOption {
+	return func(es *ExcelService) {
+		es.holidays = calendar
+	}
 }
 
-func NewExcelService(templatePath string, sheetName string) *ExcelService {
-	return &ExcelService{
+// WithReportMapping sets the cell layout of the report template
+func WithReportMapping(mapping models.ReportMapping) ExcelOption {
+	return func(es *ExcelService) {
+		es.mapping = mapping
+	}
+}
+
+// WithReportTemplates uses the active uploaded report templates for the
+// periods they cover instead of the configured template
+func WithReportTemplates(store *ReportTemplateStore) ExcelOption {
+	return func(es *ExcelService) {
+		es.reportTemplates = store
+	}
+}
+
+// WithMemberProfiles fills the names of members with a profile into reports
+// as their profile has them
+func WithMemberProfiles(store *ProfileStore) ExcelOption {
+	return func(es *ExcelService) {
+		es.profiles = store
+	}
+}
+
+func NewExcelService(templatePath string, sheetName string, opts ...ExcelOption) *ExcelService {
+	es := &ExcelService{
 		templatePath:     templatePath,
 		sourceSheet:      sheetName,
-		targetSheet:      "výkaz práce",
+		mapping:          DefaultReportMapping(),
 		idxClen:          1,
 		idxAttended:      6,
 		idxTypUdalosti:   8,
 		idxNazevUdalosti: 9,
 		idxDatum1:        11,
 		idxDatum2:        12,
+		attendance:       DefaultAttendanceRules(),
+		dateParser:       utils.NewDateParser(),
 	}
+
+	for _, opt := range opts {
+		opt(es)
+	}
+
+	return es
 }
 
 func VerifySheetExists(fileData []byte, sheetName string) (bool, []string, error) {
@@ -75,45 +240,167 @@ func VerifySheetExists(fileData []byte, sheetName string) (bool, []string, error
 	return false, sheets, nil
 }
 
-func (es *ExcelService) ParseExcelForNamesAndMonths(fileData []byte) ([]string, []int, error) {
-	if es.sourceSheet == "" {
-		return nil, nil, fmt.Errorf("source sheet name is empty")
-	}
+// sourceSheet holds all rows of a source sheet, header included
+type sourceSheet struct {
+	name string
+	rows [][]string
+}
 
-	srcFile, err := excelize.OpenReader(bytes.NewReader(fileData))
+// readSourceSheets reads the sheets of a workbook that the selections name or
+// match. Cells are read raw, so real date cells arrive as serial numbers
+// whatever their display format; date1904 tells which date system they use.
+func (es *ExcelService) readSourceSheets(fileData []byte, selections []string) (sheets []sourceSheet, date1904 bool, err error) {
+	srcFile, err := excelize.OpenReader(bytes.NewReader(fileData), excelize.Options{RawCellValue: true})
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open uploaded file: %w", err)
+		return nil, false, fmt.Errorf("failed to open uploaded file: %w", err)
 	}
 	defer srcFile.Close()
 
-	// Check if the source sheet exists
-	if _, err := srcFile.GetSheetIndex(es.sourceSheet); err != nil {
-		sheets := srcFile.GetSheetList()
-		return nil, nil, SheetNotFoundError{
-			SheetName:       es.sourceSheet,
-			AvailableSheets: sheets,
+	// Check if the source sheets exist
+	available := srcFile.GetSheetList()
+	matched, missing := MatchSheets(available, selections)
+	if len(matched) == 0 || len(missing) > 0 {
+		sheetName := strings.Join(missing, ", ")
+		if sheetName == "" {
+			sheetName = strings.Join(selections, ", ")
+		}
+		return nil, false, SheetNotFoundError{
+			SheetName:       sheetName,
+			AvailableSheets: available,
+		}
+	}
+
+	for _, name := range matched {
+		rows, err := srcFile.GetRows(name)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get rows from sheet %s: %w", name, err)
 		}
+		sheets = append(sheets, sourceSheet{name: name, rows: rows})
 	}
 
-	// Get all rows from the source sheet
-	rows, err := srcFile.GetRows(es.sourceSheet)
+	if props, err := srcFile.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+		date1904 = *props.Date1904
+	}
+
+	return sheets, date1904, nil
+}
+
+// sourceRow is a data row of one of the uploaded attendance exports
+type sourceRow struct {
+	cells    []string
+	file     int    // index of the export in the session
+	sheet    string // sheet of the export the row is on
+	number   int    // row number as shown in Excel
+	origin   string // where the row comes from, for TableRow.Origin
+	date1904 bool
+	// Same member, start and end as another row, possibly of another sheet
+	// or export; only one copy of an event is used, see copyPreference
+	duplicate bool
+}
+
+// readSources reads the data rows of the source sheets of every export, in
+// upload and workbook order, without the header rows
+func (es *ExcelService) readSources(files []models.SourceFile, pc ParseContext) ([]sourceRow, error) {
+	selections := es.sheetsFor(pc)
+	var rows []sourceRow
+	// Index of the copy used of each event
+	used := make(map[string]int)
+	for i, file := range files {
+		sheets, date1904, err := es.readSourceSheets(file.Data, selections)
+		if err != nil {
+			if len(files) > 1 && file.Filename != "" {
+				if _, ok := IsSheetNotFoundError(err); !ok {
+					err = fmt.Errorf("%s: %w", file.Filename, err)
+				}
+			}
+			return nil, err
+		}
+		for _, sheet := range sheets {
+			origin := file.Filename
+			if len(sheets) > 1 {
+				origin = strings.TrimSpace(origin + " " + sheet.name)
+			}
+			for j, cells := range sheet.rows {
+				if j == 0 {
+					continue // Skip header row
+				}
+				row := sourceRow{cells: cells, file: i, sheet: sheet.name, number: j + 1, origin: origin, date1904: date1904}
+				if key, ok := es.eventKey(row); ok {
+					if k, seen := used[key]; !seen {
+						used[key] = len(rows)
+					} else if es.copyPreference(row) > es.copyPreference(rows[k]) {
+						rows[k].duplicate = true
+						used[key] = len(rows)
+					} else {
+						row.duplicate = true
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// eventKey identifies the event of a row by member, start and end, for rows
+// where all of them can be read
+func (es *ExcelService) eventKey(row sourceRow) (string, bool) {
+	member := strings.TrimSpace(utils.SafeGetCellValue(row.cells, es.idxClen))
+	start, startErr := es.dateParser.Parse(utils.SafeGetCellValue(row.cells, es.idxDatum1), row.date1904)
+	end, endErr := es.dateParser.Parse(utils.SafeGetCellValue(row.cells, es.idxDatum2), row.date1904)
+	if member == "" || startErr != nil || endErr != nil {
+		return "", false
+	}
+	return member + "|" + start.Format("2006-01-02 15:04") + "|" + end.Format("2006-01-02 15:04"), true
+}
+
+// copyPreference ranks the copies of an event, so a confirmed copy is used
+// over an unconfirmed one, which is used over a declined one or one of an
+// excluded event type. Equal copies use the first one.
+func (es *ExcelService) copyPreference(row sourceRow) int {
+	if !es.eventTypes.Allows(strings.TrimSpace(utils.SafeGetCellValue(row.cells, es.idxTypUdalosti))) {
+		return 0
+	}
+	switch es.attendance.Status(utils.SafeGetCellValue(row.cells, es.idxAttended)) {
+	case AttendanceDeclined:
+		return 0
+	case AttendanceUnconfirmed:
+		return 1
+	}
+	return 2
+}
+
+// ParseExcelForNamesAndMonths returns the members and months of a single
+// attendance export
+func (es *ExcelService) ParseExcelForNamesAndMonths(fileData []byte) ([]string, []int, error) {
+	return es.ParseSourcesForNamesAndMonths([]models.SourceFile{{Data: fileData}}, ParseContext{})
+}
+
+// ParseSourcesForNamesAndMonths returns the members and months across all
+// attendance exports of a session
+func (es *ExcelService) ParseSourcesForNamesAndMonths(files []models.SourceFile, pc ParseContext) ([]string, []int, error) {
+	if len(es.sheetsFor(pc)) == 0 {
+		return nil, nil, fmt.Errorf("source sheet name is empty")
+	}
+
+	rows, err := es.readSources(files, pc)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get rows from sheet %s: %w", es.sourceSheet, err)
+		return nil, nil, err
 	}
 
 	nameSet := make(map[string]struct{})
 	monthSet := make(map[int]struct{})
 
-	for _, row := range rows[1:] { // Skip header row
-		clenValue := utils.SafeGetCellValue(row, es.idxClen)
+	for _, row := range rows {
+		clenValue := utils.SafeGetCellValue(row.cells, es.idxClen)
 		if clenValue != "" {
 			nameSet[clenValue] = struct{}{}
 		}
 
 		// Extract start date
-		startDateStr := utils.SafeGetCellValue(row, es.idxDatum1)
+		startDateStr := utils.SafeGetCellValue(row.cells, es.idxDatum1)
 		if startDateStr != "" {
-			startDate, err := utils.ParseDate(startDateStr)
+			startDate, err := es.dateParser.Parse(startDateStr, row.date1904)
 			if err == nil {
 				monthSet[int(startDate.Month())] = struct{}{}
 			}
@@ -138,101 +425,522 @@ func (es *ExcelService) ParseExcelForNamesAndMonths(fileData []byte) ([]string,
 	return names, months, nil
 }
 
+// EventTypes returns the distinct event types of the source sheets that the
+// configured filter allows, sorted
+func (es *ExcelService) EventTypes(files []models.SourceFile, pc ParseContext) ([]string, error) {
+	rows, err := es.readSources(files, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	typeSet := make(map[string]struct{})
+	for _, row := range rows {
+		eventType := strings.TrimSpace(utils.SafeGetCellValue(row.cells, es.idxTypUdalosti))
+		if eventType != "" && es.eventTypes.Allows(eventType) {
+			typeSet[eventType] = struct{}{}
+		}
+	}
+
+	var types []string
+	for eventType := range typeSet {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+
+	return types, nil
+}
+
+// DateParseFailures lists the date cells of member rows in the source sheets
+// that cannot be read, so the events they belong to would be left out
+func (es *ExcelService) DateParseFailures(files []models.SourceFile, pc ParseContext) ([]models.ParseFailure, error) {
+	rows, err := es.readSources(files, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []models.ParseFailure
+	for _, row := range rows {
+		if utils.SafeGetCellValue(row.cells, es.idxClen) == "" {
+			continue
+		}
+		for _, col := range []int{es.idxDatum1, es.idxDatum2} {
+			value := utils.SafeGetCellValue(row.cells, col)
+			if _, err := es.dateParser.Parse(value, row.date1904); err == nil {
+				continue
+			}
+			column, _ := excelize.ColumnNumberToName(col + 1)
+			failures = append(failures, models.ParseFailure{
+				File:   files[row.file].Filename,
+				Sheet:  row.sheet,
+				Row:    row.number,
+				Column: column,
+				Value:  value,
+			})
+		}
+	}
+
+	return failures, nil
+}
+
 func (es *ExcelService) ExtractTableData(fileData []byte, name string, month int) ([]models.TableRow, error) {
-	srcFile, err := excelize.OpenReader(bytes.NewReader(fileData))
+	tableData, _, err := es.ExtractTableDataWithOptions([]models.SourceFile{{Data: fileData}}, ParseContext{}, name, month, ExtractOptions{})
+	return tableData, err
+}
+
+// ExtractTableDataWithOptions is ExtractTableData across all attendance
+// exports of a session, with a selection of event types and unconfirmed
+// attendance. It also returns how many events of the member in the month
+// were left out, by reason. Rows repeated in several exports count once,
+// preferring a copy with confirmed attendance.
+func (es *ExcelService) ExtractTableDataWithOptions(files []models.SourceFile, pc ParseContext, name string, month int, opts ExtractOptions) ([]models.TableRow, map[string]int, error) {
+	rows, err := es.readSources(files, pc)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+		return nil, nil, err
 	}
-	defer srcFile.Close()
 
-	// Assume data is in a specific sheet and columns
-	rows, err := srcFile.GetRows(es.sourceSheet)
+	tableData, excluded := es.extractRows(rows, name, month, opts)
+	return tableData, excluded, nil
+}
+
+// ExtractMembersTableData is ExtractTableDataWithOptions for every member of
+// the exports at once, reading them only once. Left out events are not
+// counted.
+func (es *ExcelService) ExtractMembersTableData(files []models.SourceFile, pc ParseContext, month int, opts ExtractOptions) (map[string][]models.TableRow, error) {
+	rows, err := es.readSources(files, pc)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get rows: %w", err)
+		return nil, err
+	}
+
+	rowsByMember := make(map[string][]sourceRow)
+	for _, row := range rows {
+		member := utils.SafeGetCellValue(row.cells, es.idxClen)
+		rowsByMember[member] = append(rowsByMember[member], row)
+	}
+
+	tableData := make(map[string][]models.TableRow, len(rowsByMember))
+	for member, memberRows := range rowsByMember {
+		tableData[member], _ = es.extractRows(memberRows, member, month, opts)
 	}
+	return tableData, nil
+}
 
+// extractRows turns the source rows of a member that touch a month into
+// timesheet rows and counts the left out events by reason
+func (es *ExcelService) extractRows(rows []sourceRow, name string, month int, opts ExtractOptions) ([]models.TableRow, map[string]int) {
 	var tableData []models.TableRow
-	for _, row := range rows[1:] { // Skip header row
-		member := utils.SafeGetCellValue(row, es.idxClen)
-		if member != name {
+	excluded := make(map[string]int)
+	for _, row := range rows {
+		member := utils.SafeGetCellValue(row.cells, es.idxClen)
+		if member != name || row.duplicate {
 			continue
 		}
 
-		startDateStr := utils.SafeGetCellValue(row, es.idxDatum1)
-		startDate, err := utils.ParseDate(startDateStr)
+		startDateStr := utils.SafeGetCellValue(row.cells, es.idxDatum1)
+		startDate, err := es.dateParser.Parse(startDateStr, row.date1904)
 		if err != nil {
+			excluded[ExcludedInvalidDate]++
 			continue
 		}
-		if int(startDate.Month()) != month {
+
+		endDateStr := utils.SafeGetCellValue(row.cells, es.idxDatum2)
+		endDate, err := es.dateParser.Parse(endDateStr, row.date1904)
+		if err != nil {
+			excluded[ExcludedInvalidDate]++
 			continue
 		}
 
-		endDateStr := utils.SafeGetCellValue(row, es.idxDatum2)
-		endDate, err := utils.ParseDate(endDateStr)
-		if err != nil {
+		// Only events touching the month matter, for the rows and the counts
+		if int(startDate.Month()) != month && int(endDate.Month()) != month {
 			continue
 		}
 
-		attended := utils.SafeGetCellValue(row, es.idxAttended)
+		eventType := strings.TrimSpace(utils.SafeGetCellValue(row.cells, es.idxTypUdalosti))
+		if !es.eventTypes.Allows(eventType) || (len(opts.EventTypes) > 0 && !containsEventType(opts.EventTypes, eventType)) {
+			excluded[ExcludedEventType]++
+			continue
+		}
 
-		if attended != "ano" {
-			if int(startDate.Month()) == month {
+		tentative := false
+		switch es.attendance.Status(utils.SafeGetCellValue(row.cells, es.idxAttended)) {
+		case AttendanceDeclined:
+			excluded[ExcludedDeclined]++
+			continue
+		case AttendanceUnconfirmed:
+			if !opts.IncludeTentative {
+				excluded[ExcludedUnconfirmed]++
 				continue
 			}
+			tentative = true
 		}
 
-		dateEntry := startDate.Format("2006-01-02")
-		timeStartEntry := startDate.Format("15:04")
-		timeEndEntry := endDate.Format("15:04")
-		note := utils.SafeGetCellValue(row, es.idxNazevUdalosti)
+		// Events crossing midnight become one row per day, so keep only the
+		// days that fall into the requested month
+		note := utils.SafeGetCellValue(row.cells, es.idxNazevUdalosti)
+		for _, dayRow := range SplitEvent(startDate, endDate, note, es.splitOptions) {
+			if date, err := time.Parse("2006-01-02", dayRow.Date); err == nil && int(date.Month()) == month {
+				dayRow.EventType = eventType
+				dayRow.Tentative = tentative
+				dayRow.Origin = row.origin
+				tableData = append(tableData, dayRow)
+			}
+		}
+	}
+
+	return tableData, excluded
+}
 
-		tableData = append(tableData, models.TableRow{
-			Date:      dateEntry,
-			StartTime: timeStartEntry,
-			EndTime:   timeEndEntry,
-			Note:      note,
-		})
+// IsGeneratedReport reports whether fileData is a timesheet produced by
+// ProcessExcelFile rather than an export of the attendance system. Such files
+// contain the report target sheet with a filled in name but no source sheet.
+func (es *ExcelService) IsGeneratedReport(fileData []byte) bool {
+	f, err := excelize.OpenReader(bytes.NewReader(fileData))
+	if err != nil {
+		return false
 	}
+	defer f.Close()
 
-	return tableData, nil
+	if matched, _ := MatchSheets(f.GetSheetList(), es.sheetsFor(ParseContext{})); len(matched) > 0 {
+		return false
+	}
+	_, ok := es.generatedReportMapping(f)
+	return ok
+}
+
+// generatedReportMapping returns the mapping of the template a generated
+// report was made from, recognised by the target sheet with a filled in name
+func (es *ExcelService) generatedReportMapping(f *excelize.File) (models.ReportMapping, bool) {
+	mappings := []models.ReportMapping{es.mapping}
+	if es.reportTemplates != nil {
+		mappings = append(mappings, es.reportTemplates.Mappings()...)
+	}
+
+	for _, mapping := range mappings {
+		if idx, err := f.GetSheetIndex(mapping.TargetSheet); err != nil || idx == -1 {
+			continue
+		}
+		firstname, _ := f.GetCellValue(mapping.TargetSheet, mapping.FirstNameCell)
+		lastname, _ := f.GetCellValue(mapping.TargetSheet, mapping.LastNameCell)
+		if strings.TrimSpace(firstname+lastname) != "" {
+			return mapping, true
+		}
+	}
+	return models.ReportMapping{}, false
+}
+
+// ExtractReportData reads the member name and the entries back from a report
+// generated by ProcessExcelFile, including its continuation sheets.
+func (es *ExcelService) ExtractReportData(fileData []byte) (string, []models.TableRow, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(fileData), excelize.Options{RawCellValue: true})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer f.Close()
+
+	mapping, ok := es.generatedReportMapping(f)
+	if !ok {
+		mapping = es.mapping
+	}
+
+	if idx, err := f.GetSheetIndex(mapping.TargetSheet); err != nil || idx == -1 {
+		return "", nil, SheetNotFoundError{SheetName: mapping.TargetSheet, AvailableSheets: f.GetSheetList()}
+	}
+
+	firstname, err := f.GetCellValue(mapping.TargetSheet, mapping.FirstNameCell)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read firstname: %w", err)
+	}
+	lastname, err := f.GetCellValue(mapping.TargetSheet, mapping.LastNameCell)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read lastname: %w", err)
+	}
+	// Member names are "Lastname Firstname" as in the attendance export
+	name := strings.TrimSpace(strings.TrimSpace(lastname) + " " + strings.TrimSpace(firstname))
+
+	date1904 := false
+	if props, err := f.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+		date1904 = *props.Date1904
+	}
+
+	var tableData []models.TableRow
+	for _, sheet := range reportSheetNames(mapping, len(f.GetSheetList())*mapping.MaxRows) {
+		if idx, err := f.GetSheetIndex(sheet); err != nil || idx == -1 {
+			break
+		}
+
+		for rowNum := mapping.StartRow; rowNum < mapping.StartRow+mapping.MaxRows; rowNum++ {
+			cell := func(column string) string {
+				value, _ := f.GetCellValue(sheet, fmt.Sprintf("%s%d", column, rowNum))
+				return strings.TrimSpace(value)
+			}
+
+			date, ok := reportCellDate(cell(mapping.DateColumn), date1904)
+			if !ok {
+				continue
+			}
+
+			tableData = append(tableData, models.TableRow{
+				Date:      date.Format("2006-01-02"),
+				StartTime: reportCellTime(cell(mapping.StartTimeColumn)),
+				EndTime:   reportCellTime(cell(mapping.EndTimeColumn)),
+				Note:      cell(mapping.NoteColumn),
+			})
+		}
+	}
+
+	return name, tableData, nil
+}
+
+// reportCellDate parses a date cell holding either a serial number or text
+func reportCellDate(value string, date1904 bool) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		date, err := excelize.ExcelDateToTime(serial, date1904)
+		return date, err == nil
+	}
+	for _, layout := range []string{"2006-01-02", "2.1.2006", "02.01.2006"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// reportCellTime converts a time cell to HH:MM. Serial values are fractions
+// of a day, and the 24:00 written for midnight becomes 00:00 again.
+func reportCellTime(value string) string {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	minutes := int(math.Round(serial*24*60)) % (24 * 60)
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
 }
 
-// ProcessExcelFile generates an Excel report based on input data
+// ProcessExcelFile generates an Excel report based on input data, from the
+// template used for the period of the report
 func (es *ExcelService) ProcessExcelFile(filterName string, tableData []models.TableRow) (*excelize.File, error) {
 	startTime := time.Now()
+
 	// Load the existing Excel template
-	templateFile, err := excelize.OpenFile(es.templatePath)
+	templateFile, mapping, err := es.openReportTemplate(ReportPeriod(tableData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to open template file: %w", err)
+		return nil, err
 	}
 	// Note: Do not defer closing templateFile here since we'll return it
 
+	if err := es.fillReport(templateFile, mapping, filterName, tableData); err != nil {
+		templateFile.Close()
+		return nil, err
+	}
+
+	m := metrics.GetMetrics()
+	m.RecordFileProcessed(metrics.StageProcess, metrics.StatusSuccess)
+	m.RecordProcessingDuration(metrics.StageProcess, time.Since(startTime))
+
+	return templateFile, nil
+}
+
+// GenerateSample fills an uploaded report template with sample entries, to
+// preview how reports generated from it look
+func (es *ExcelService) GenerateSample(templateData []byte, mapping models.ReportMapping, name string, tableData []models.TableRow) (*excelize.File, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(templateData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open template file: %w", err)
+	}
+	if err := es.fillReport(f, mapping, name, tableData); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// openReportTemplate opens the active uploaded template covering the report
+// period, or the configured template when there is none
+func (es *ExcelService) openReportTemplate(period time.Time) (*excelize.File, models.ReportMapping, error) {
+	if es.reportTemplates != nil {
+		if tmpl, data, ok := es.reportTemplates.For(period); ok {
+			f, err := excelize.OpenReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, tmpl.Mapping, fmt.Errorf("failed to open report template %q: %w", tmpl.Name, err)
+			}
+			return f, tmpl.Mapping, nil
+		}
+	}
+
+	f, err := excelize.OpenFile(es.templatePath)
+	if err != nil {
+		return nil, es.mapping, fmt.Errorf("failed to open template file: %w", err)
+	}
+	return f, es.mapping, nil
+}
+
+// reportMapping returns the mapping of the template used for the report
+// period
+func (es *ExcelService) reportMapping(period time.Time) models.ReportMapping {
+	if es.reportTemplates != nil {
+		if tmpl, _, ok := es.reportTemplates.For(period); ok {
+			return tmpl.Mapping
+		}
+	}
+	return es.mapping
+}
+
+// fillReport writes the member name and the entries into a report template
+func (es *ExcelService) fillReport(templateFile *excelize.File, mapping models.ReportMapping, filterName string, tableData []models.TableRow) error {
 	// Check if the target sheet exists in the template file
-	if _, err := templateFile.GetSheetIndex(es.targetSheet); err != nil {
-		return nil, fmt.Errorf("sheet %q does not exist in the template file", es.targetSheet)
+	if idx, err := templateFile.GetSheetIndex(mapping.TargetSheet); err != nil || idx == -1 {
+		return fmt.Errorf("sheet %q does not exist in the template file", mapping.TargetSheet)
 	}
 
 	// Split the filterName into firstname and lastname
-	firstname, lastname := utils.SplitName(filterName)
+	firstname, lastname := es.SplitName(filterName)
 
-	// Fill firstname and lastname into B3 and B4
-	if err := templateFile.SetCellValue(es.targetSheet, "B3", firstname); err != nil {
-		return nil, fmt.Errorf("failed to set firstname: %w", err)
+	// Fill firstname and lastname into the mapped cells (B3 and B4)
+	if err := templateFile.SetCellValue(mapping.TargetSheet, mapping.FirstNameCell, firstname); err != nil {
+		return fmt.Errorf("failed to set firstname: %w", err)
 	}
 
-	if err := templateFile.SetCellValue(es.targetSheet, "B4", lastname); err != nil {
-		return nil, fmt.Errorf("failed to set lastname: %w", err)
+	if err := templateFile.SetCellValue(mapping.TargetSheet, mapping.LastNameCell, lastname); err != nil {
+		return fmt.Errorf("failed to set lastname: %w", err)
 	}
 
-	// Starting positions
-	startRow := 7 // Data starts from row 7
-	maxRows := 31 // Limit to 31 entries (rows 7 to 37)
+	// Entries that don't fit on the target sheet continue on copies of it
+	sheetNames := reportSheetNames(mapping, len(tableData))
+	if err := cloneSheet(templateFile, mapping.TargetSheet, sheetNames[1:]); err != nil {
+		return err
+	}
+
+	for page, sheet := range sheetNames {
+		first := page * mapping.MaxRows
+		last := min(first+mapping.MaxRows, len(tableData))
+		if err := es.fillReportSheet(templateFile, mapping, sheet, tableData[first:last]); err != nil {
+			return err
+		}
+	}
+
+	// Drop the cached formula results from the template so spreadsheet
+	// applications recalculate durations and totals when the file is opened
+	if err := templateFile.UpdateLinkedValue(); err != nil {
+		return fmt.Errorf("failed to reset formula values: %w", err)
+	}
+
+	return nil
+}
+
+// ApprovalLabels are the texts written next to the approval stamped into a
+// report
+type ApprovalLabels struct {
+	ApprovedBy string
+	ApprovedAt string
+}
+
+// StampApproval writes the approver and approval date into the cells of the
+// mapping of the template the report of tableData was generated from, on
+// every sheet the report is spread over. Mappings without approval cells
+// leave the report unchanged.
+func (es *ExcelService) StampApproval(f *excelize.File, tableData []models.TableRow, approver string, approvedAt time.Time, labels ApprovalLabels) error {
+	mapping := es.reportMapping(ReportPeriod(tableData))
+
+	stamps := []struct {
+		cell  string
+		value interface{}
+	}{
+		{mapping.ApprovedByLabelCell, labels.ApprovedBy},
+		{mapping.ApprovedAtLabelCell, labels.ApprovedAt},
+		{mapping.ApprovedByCell, approver},
+		{mapping.ApprovedAtCell, approvedAt.Format("02.01.2006")},
+	}
+	for _, sheet := range reportSheetNames(mapping, len(tableData)) {
+		for _, stamp := range stamps {
+			if stamp.cell == "" {
+				continue
+			}
+			if err := f.SetCellValue(sheet, stamp.cell, stamp.value); err != nil {
+				return fmt.Errorf("failed to stamp approval at %s!%s: %w", sheet, stamp.cell, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReportMappingFunc returns the mapping of the template the report of
+// tableData is generated from
+type ReportMappingFunc func(tableData []models.TableRow) models.ReportMapping
+
+// FixedReportMapping returns a ReportMappingFunc that always uses mapping
+func FixedReportMapping(mapping models.ReportMapping) ReportMappingFunc {
+	return func([]models.TableRow) models.ReportMapping {
+		return mapping
+	}
+}
+
+// ReportMappingFor returns the mapping of the template the report of
+// tableData is generated from, which depends on its period. It is a
+// ReportMappingFunc.
+func (es *ExcelService) ReportMappingFor(tableData []models.TableRow) models.ReportMapping {
+	return es.reportMapping(ReportPeriod(tableData))
+}
+
+// ReportSheetNames returns the names of the sheets the report of tableData
+// is spread over: the target sheet followed by numbered continuation sheets.
+func (es *ExcelService) ReportSheetNames(tableData []models.TableRow) []string {
+	return reportSheetNames(es.ReportMappingFor(tableData), len(tableData))
+}
+
+// reportSheetNames returns the names of the sheets needed to hold rowCount
+// entries with a mapping
+func reportSheetNames(mapping models.ReportMapping, rowCount int) []string {
+	names := []string{mapping.TargetSheet}
+	// Without room for entries, more sheets would not help
+	if mapping.MaxRows < 1 {
+		return names
+	}
+	for page := 2; (page-1)*mapping.MaxRows < rowCount; page++ {
+		suffix := fmt.Sprintf(" (%d)", page)
+		base := []rune(mapping.TargetSheet)
+		if maxLen := excelize.MaxSheetNameLength - len([]rune(suffix)); len(base) > maxLen {
+			base = base[:maxLen]
+		}
+		names = append(names, string(base)+suffix)
+	}
+	return names
+}
+
+// cloneSheet creates copies of the source sheet under the given names
+func cloneSheet(f *excelize.File, source string, names []string) error {
+	sourceIdx, err := f.GetSheetIndex(source)
+	if err != nil {
+		return fmt.Errorf("failed to find sheet %q: %w", source, err)
+	}
+
+	for _, name := range names {
+		idx, err := f.NewSheet(name)
+		if err != nil {
+			return fmt.Errorf("failed to create continuation sheet %q: %w", name, err)
+		}
+		if err := f.CopySheet(sourceIdx, idx); err != nil {
+			return fmt.Errorf("failed to copy sheet %q to %q: %w", source, name, err)
+		}
+	}
+
+	return nil
+}
+
+// fillReportSheet writes up to MaxRows entries and their total into a report sheet
+func (es *ExcelService) fillReportSheet(f *excelize.File, mapping models.ReportMapping, sheet string, tableData []models.TableRow) error {
+	totals := ComputeTotals(tableData)
+
+	dayOffStyle, err := es.dayOffStyle(f, mapping, sheet)
+	if err != nil {
+		return err
+	}
 
 	// Process the tableData and fill dates and times
 	for i, row := range tableData {
-		if i >= maxRows {
-			break // Limit to maxRows entries
-		}
+		rowNum := mapping.StartRow + i
 
 		// Parse the date string into time.Time
 		date, err := time.Parse("2006-01-02", row.Date)
@@ -241,63 +949,134 @@ func (es *ExcelService) ProcessExcelFile(filterName string, tableData []models.T
 			continue // Skip rows with invalid date
 		}
 
-		// Set the date into column A
-		cellDate := fmt.Sprintf("A%d", startRow+i)
-		if err := templateFile.SetCellValue(es.targetSheet, cellDate, date); err != nil {
-			return nil, fmt.Errorf("failed to set date at %s: %w", cellDate, err)
+		// Set the date
+		cellDate := fmt.Sprintf("%s%d", mapping.DateColumn, rowNum)
+		if err := f.SetCellValue(sheet, cellDate, date); err != nil {
+			return fmt.Errorf("failed to set date at %s: %w", cellDate, err)
 		}
 
-		startTime, err := time.Parse("15:04", row.StartTime)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse startTime: %w", err)
+		// Mark public holidays and shade days off, which are paid differently
+		if es.holidays != nil {
+			_, isHoliday := es.holidays.Holiday(date)
+			if mapping.HolidayColumn != "" {
+				holidayValue := "ne"
+				if isHoliday {
+					holidayValue = "ano"
+				}
+				cellHoliday := fmt.Sprintf("%s%d", mapping.HolidayColumn, rowNum)
+				if err := f.SetCellValue(sheet, cellHoliday, holidayValue); err != nil {
+					return fmt.Errorf("failed to set holiday at %s: %w", cellHoliday, err)
+				}
+			}
+			if dayOffStyle != 0 && (isHoliday || holidays.IsWeekend(date)) {
+				if err := f.SetCellStyle(sheet, cellDate, cellDate, dayOffStyle); err != nil {
+					return fmt.Errorf("failed to set day off style at %s: %w", cellDate, err)
+				}
+			}
 		}
-		endTime, err := time.Parse("15:04", row.EndTime)
+
+		// An end time of midnight is written as 24:00, as the template expects
+		startTime, endTime, err := utils.ParseTimeRange(row.StartTime, row.EndTime)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse endTime: %w", err)
+			return fmt.Errorf("failed to parse time range: %w", err)
 		}
 		serialStartTime := utils.TimeToSerial(startTime.Hour(), startTime.Minute(), startTime.Second())
 		serialEndTime := utils.TimeToSerial(endTime.Hour(), endTime.Minute(), endTime.Second())
+		if endTime.Day() != startTime.Day() {
+			serialEndTime += 1
+		}
 
-		// Set start time into column B
-		cellStartTime := fmt.Sprintf("B%d", startRow+i)
-		if err := templateFile.SetCellValue(es.targetSheet, cellStartTime, serialStartTime); err != nil {
-			return nil, fmt.Errorf("failed to set start time at %s: %w", cellStartTime, err)
+		// Set start time
+		cellStartTime := fmt.Sprintf("%s%d", mapping.StartTimeColumn, rowNum)
+		if err := f.SetCellValue(sheet, cellStartTime, serialStartTime); err != nil {
+			return fmt.Errorf("failed to set start time at %s: %w", cellStartTime, err)
 		}
 
-		// Set end time into column C
-		cellEndTime := fmt.Sprintf("C%d", startRow+i)
-		if err := templateFile.SetCellValue(es.targetSheet, cellEndTime, serialEndTime); err != nil {
-			return nil, fmt.Errorf("failed to set end time at %s: %w", cellEndTime, err)
+		// Set end time
+		cellEndTime := fmt.Sprintf("%s%d", mapping.EndTimeColumn, rowNum)
+		if err := f.SetCellValue(sheet, cellEndTime, serialEndTime); err != nil {
+			return fmt.Errorf("failed to set end time at %s: %w", cellEndTime, err)
 		}
 
-		if err := templateFile.SetCellStyle(es.targetSheet, cellStartTime, cellEndTime, 25); err != nil {
-			return nil, fmt.Errorf("failed to set start time style at %s: %w", cellStartTime, err)
+		if err := f.SetCellStyle(sheet, cellStartTime, cellEndTime, mapping.TimeStyleID); err != nil {
+			return fmt.Errorf("failed to set start time style at %s: %w", cellStartTime, err)
 		}
 
-		// Set note into column F
-		cellNote := fmt.Sprintf("F%d", startRow+i)
-		if err := templateFile.SetCellValue(es.targetSheet, cellNote, row.Note); err != nil {
-			return nil, fmt.Errorf("failed to set note at %s: %w", cellNote, err)
+		// Set note
+		cellNote := fmt.Sprintf("%s%d", mapping.NoteColumn, rowNum)
+		if err := f.SetCellValue(sheet, cellNote, row.Note); err != nil {
+			return fmt.Errorf("failed to set note at %s: %w", cellNote, err)
+		}
+
+		// Set the row duration unless the template computes it with a formula
+		if mapping.HoursColumn != "" {
+			cellHours := fmt.Sprintf("%s%d", mapping.HoursColumn, rowNum)
+			if err := setValueUnlessFormula(f, sheet, cellHours, totals.RowHours[i]); err != nil {
+				return fmt.Errorf("failed to set hours at %s: %w", cellHours, err)
+			}
 		}
 	}
 
-	m := metrics.GetMetrics()
-	m.RecordFileProcessed(metrics.StageProcess, metrics.StatusSuccess)
-	m.RecordProcessingDuration(metrics.StageProcess, time.Since(startTime))
+	if mapping.TotalHoursCell != "" {
+		if err := setValueUnlessFormula(f, sheet, mapping.TotalHoursCell, totals.TotalHours); err != nil {
+			return fmt.Errorf("failed to set total hours at %s: %w", mapping.TotalHoursCell, err)
+		}
+	}
 
-	return templateFile, nil
+	return nil
 }
 
-func (es *ExcelService) SetSourceSheet(sheetName string) {
-	if sheetName == "" {
-		log.Println("No sheet name provided")
-		return
+// dayOffStyle returns a copy of the style of the first date cell filled with
+// the day off color, or 0 when days off are not shaded
+func (es *ExcelService) dayOffStyle(f *excelize.File, mapping models.ReportMapping, sheet string) (int, error) {
+	if es.holidays == nil || mapping.DayOffFillColor == "" {
+		return 0, nil
 	}
 
-	log.Printf("Setting source sheet to: '%s'", sheetName)
-	es.sourceSheet = sheetName
+	cell := fmt.Sprintf("%s%d", mapping.DateColumn, mapping.StartRow)
+	styleID, err := f.GetCellStyle(sheet, cell)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get date style at %s: %w", cell, err)
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read date style at %s: %w", cell, err)
+	}
+
+	style.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{mapping.DayOffFillColor}}
+	return f.NewStyle(style)
 }
 
+// setValueUnlessFormula writes value into cell, leaving cells that hold a
+// formula in the template untouched.
+func setValueUnlessFormula(f *excelize.File, sheet, cell string, value interface{}) error {
+	formula, err := f.GetCellFormula(sheet, cell)
+	if err != nil {
+		return err
+	}
+	if formula != "" {
+		return nil
+	}
+	return f.SetCellValue(sheet, cell, value)
+}
+
+// GetSourceSheet returns the configured source sheet, read by sessions that
+// did not choose other sheets
 func (es *ExcelService) GetSourceSheet() string {
 	return es.sourceSheet
 }
+
+// Holidays returns the holiday calendar, nil when none is configured
+func (es *ExcelService) Holidays() *holidays.Calendar {
+	return es.holidays
+}
+
+// SplitName returns the first and last name of a member, from the member's
+// profile when there is one
+func (es *ExcelService) SplitName(member string) (firstname, lastname string) {
+	return es.profiles.SplitName(member)
+}
+
+func (es *ExcelService) GetReportMapping() models.ReportMapping {
+	return es.mapping
+}
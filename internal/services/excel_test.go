@@ -1,8 +1,16 @@
 package services
 
 import (
+	"bytes"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/xuri/excelize/v2"
+
+	"timesheet-filler/internal/holidays"
+	"timesheet-filler/internal/models"
 	"timesheet-filler/internal/testutil"
 )
 
@@ -92,3 +100,574 @@ func TestExtractTableData(t *testing.T) {
 		t.Errorf("Expected 0 rows for non-existent user, got %d", len(noData))
 	}
 }
+
+func TestExtractMembersTableData(t *testing.T) {
+	testFileData := testutil.CreateTestExcelFile(t)
+	excelService := NewExcelService("test_template.xlsx", "docházka realizačního týmu")
+	files := []models.SourceFile{{Data: testFileData}}
+
+	byMember, err := excelService.ExtractMembersTableData(files, ParseContext{}, 1, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Every member gets the rows they would get on their own
+	for _, name := range []string{"Test User", "Another User"} {
+		want, _, err := excelService.ExtractTableDataWithOptions(files, ParseContext{}, name, 1, ExtractOptions{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		got := byMember[name]
+		if len(got) != len(want) || len(got) == 0 {
+			t.Fatalf("Expected %d rows for %s, got %d", len(want), name, len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s row %d: expected %+v, got %+v", name, i, want[i], got[i])
+			}
+		}
+	}
+}
+
+func TestExtractTableDataEventTypes(t *testing.T) {
+	testFileData := testutil.CreateTestExcelFile(t)
+
+	excelService := NewExcelService("test_template.xlsx", "docházka realizačního týmu",
+		WithEventTypeFilter(EventTypeFilter{Exclude: []string{"GAME"}}))
+
+	types, err := excelService.EventTypes([]models.SourceFile{{Data: testFileData}}, ParseContext{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(types) != 1 || types[0] != "training" {
+		t.Errorf("Expected only the 'training' type, got %v", types)
+	}
+
+	tableData, err := excelService.ExtractTableData(testFileData, "Test User", 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tableData) != 1 || tableData[0].EventType != "training" {
+		t.Errorf("Expected the excluded game to be dropped, got %+v", tableData)
+	}
+
+	// A selection narrows the configured filter further
+	excelService = NewExcelService("test_template.xlsx", "docházka realizačního týmu")
+	tableData, excluded, err := excelService.ExtractTableDataWithOptions([]models.SourceFile{{Data: testFileData}}, ParseContext{}, "Test User", 1, ExtractOptions{EventTypes: []string{"game"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tableData) != 1 || tableData[0].Note != "Championship" {
+		t.Errorf("Expected only the selected game, got %+v", tableData)
+	}
+	if excluded[ExcludedEventType] != 1 {
+		t.Errorf("Expected 1 event excluded by type, got %d", excluded[ExcludedEventType])
+	}
+
+	totals := ComputeTotals([]models.TableRow{
+		{Date: "2023-01-15", StartTime: "18:00", EndTime: "20:00", EventType: "training"},
+		{Date: "2023-01-16", StartTime: "18:00", EndTime: "19:30", EventType: "training"},
+		{Date: "2023-01-22", StartTime: "15:30", EndTime: "17:30"},
+	})
+	if totals.TypeHours["training"] != 3.5 || len(totals.TypeHours) != 1 {
+		t.Errorf("Expected 3.5 training hours, got %v", totals.TypeHours)
+	}
+}
+
+func TestExtractTableDataAttendance(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "docházka realizačního týmu"
+	f.NewSheet(sheet)
+	f.SetSheetRow(sheet, "A1", &[]string{"ID", "Člen", "", "", "", "", "Účast potvrzena", "", "Typ události", "Název události", "", "Od", "Do"})
+	entries := []struct {
+		attended string
+		start    string
+	}{
+		{"Ano", "2023-01-02 18:00"},
+		{"✓", "2023-01-03 18:00"},
+		{" YES ", "2023-01-04 18:00"},
+		{"ne", "2023-01-05 18:00"},
+		{"", "2023-01-06 18:00"},
+		{"možná", "2023-01-07 18:00"},
+		{"ne", "2023-02-05 18:00"}, // other month, not counted
+		{"ano", "invalid"},
+	}
+	for i, e := range entries {
+		end := e.start
+		if len(end) == len("2023-01-02 18:00") {
+			end = end[:11] + "20:00"
+		}
+		f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+2), &[]string{"", "Test User", "", "", "", "", e.attended, "", "training", "Practice", "", e.start, end})
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	excelService := NewExcelService("", sheet)
+	tableData, excluded, err := excelService.ExtractTableDataWithOptions([]models.SourceFile{{Data: buf.Bytes()}}, ParseContext{}, "Test User", 1, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tableData) != 3 {
+		t.Errorf("Expected 3 confirmed rows, got %d: %+v", len(tableData), tableData)
+	}
+	want := map[string]int{ExcludedDeclined: 1, ExcludedUnconfirmed: 2, ExcludedInvalidDate: 1}
+	for reason, count := range want {
+		if excluded[reason] != count {
+			t.Errorf("Expected %d rows excluded as %s, got %d", count, reason, excluded[reason])
+		}
+	}
+
+	tableData, excluded, _ = excelService.ExtractTableDataWithOptions([]models.SourceFile{{Data: buf.Bytes()}}, ParseContext{}, "Test User", 1, ExtractOptions{IncludeTentative: true})
+	tentative := 0
+	for _, row := range tableData {
+		if row.Tentative {
+			tentative++
+		}
+	}
+	if len(tableData) != 5 || tentative != 2 || excluded[ExcludedUnconfirmed] != 0 {
+		t.Errorf("Expected 5 rows with 2 tentative, got %d rows with %d tentative", len(tableData), tentative)
+	}
+
+	// Custom values replace the defaults
+	excelService = NewExcelService("", sheet, WithAttendanceRules(AttendanceRules{Accepted: []string{"možna"}}))
+	tableData, _, _ = excelService.ExtractTableDataWithOptions([]models.SourceFile{{Data: buf.Bytes()}}, ParseContext{}, "Test User", 1, ExtractOptions{})
+	if len(tableData) != 1 || tableData[0].Date != "2023-01-07" {
+		t.Errorf("Expected only the custom accepted value, got %+v", tableData)
+	}
+}
+
+func TestExtractTableDataDateCells(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "docházka realizačního týmu"
+	f.NewSheet(sheet)
+	f.SetSheetRow(sheet, "A1", &[]string{"ID", "Člen", "", "", "", "", "Účast potvrzena", "", "Typ události", "Název události", "", "Od", "Do"})
+	f.SetSheetRow(sheet, "A2", &[]interface{}{"", "Test User", "", "", "", "", "ano", "", "training", "Serial", "",
+		time.Date(2024, 3, 15, 18, 0, 0, 0, time.UTC), time.Date(2024, 3, 15, 20, 30, 0, 0, time.UTC)})
+	f.SetSheetRow(sheet, "A3", &[]string{"", "Test User", "", "", "", "", "ano", "", "training", "Czech", "", "16.3.2024 9:00", "16.3.2024 11:00"})
+	f.SetSheetRow(sheet, "A4", &[]string{"", "Test User", "", "", "", "", "ano", "", "training", "Seconds", "", "2024-03-17 10:00:00", "2024-03-17 12:00:00"})
+	f.SetSheetRow(sheet, "A5", &[]string{"", "Test User", "", "", "", "", "ano", "", "training", "Broken", "", "po 18. března", "2024-03-18 12:00"})
+	// A custom number format must not matter, the raw serial is read
+	style, _ := f.NewStyle(&excelize.Style{CustomNumFmt: stringPtr("d.m.yyyy h:mm")})
+	f.SetCellStyle(sheet, "L2", "M2", style)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	excelService := NewExcelService("", sheet)
+	tableData, excluded, err := excelService.ExtractTableDataWithOptions([]models.SourceFile{{Data: buf.Bytes()}}, ParseContext{}, "Test User", 3, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []models.TableRow{
+		{Date: "2024-03-15", StartTime: "18:00", EndTime: "20:30"},
+		{Date: "2024-03-16", StartTime: "09:00", EndTime: "11:00"},
+		{Date: "2024-03-17", StartTime: "10:00", EndTime: "12:00"},
+	}
+	if len(tableData) != len(want) {
+		t.Fatalf("Expected %d rows, got %d: %+v", len(want), len(tableData), tableData)
+	}
+	for i, row := range want {
+		got := tableData[i]
+		if got.Date != row.Date || got.StartTime != row.StartTime || got.EndTime != row.EndTime {
+			t.Errorf("Expected row %d to be %s %s-%s, got %s %s-%s", i, row.Date, row.StartTime, row.EndTime, got.Date, got.StartTime, got.EndTime)
+		}
+	}
+	if excluded[ExcludedInvalidDate] != 1 {
+		t.Errorf("Expected 1 row excluded for its date, got %d", excluded[ExcludedInvalidDate])
+	}
+
+	failures, err := excelService.DateParseFailures([]models.SourceFile{{Data: buf.Bytes()}}, ParseContext{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(failures) != 1 || failures[0] != (models.ParseFailure{Sheet: sheet, Row: 5, Column: "L", Value: "po 18. března"}) {
+		t.Errorf("Expected the broken cell L5 to be reported, got %+v", failures)
+	}
+}
+
+func TestExtractTableDataMergedSources(t *testing.T) {
+	sheet := "docházka realizačního týmu"
+	newExport := func(rows ...[]string) []byte {
+		f := excelize.NewFile()
+		defer f.Close()
+		f.NewSheet(sheet)
+		f.SetSheetRow(sheet, "A1", &[]string{"ID", "Člen", "", "", "", "", "Účast potvrzena", "", "Typ události", "Název události", "", "Od", "Do"})
+		for i, row := range rows {
+			f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+2), &row)
+		}
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	files := []models.SourceFile{
+		{Filename: "muzi.xlsx", Data: newExport(
+			[]string{"1", "Test User", "", "", "", "", "ano", "", "trénink", "Men", "", "2024-03-04 18:00", "2024-03-04 20:00"},
+			[]string{"2", "Test User", "", "", "", "", "ano", "", "zápas", "Game", "", "2024-03-09 10:00", "2024-03-09 12:00"},
+		)},
+		{Filename: "zeny.xlsx", Data: newExport(
+			[]string{"1", "Test User", "", "", "", "", "ano", "", "trénink", "Women", "", "2024-03-05 18:00", "2024-03-05 20:00"},
+			// The same game listed for both teams
+			[]string{"2", "Test User", "", "", "", "", "ano", "", "zápas", "Game", "", "2024-03-09 10:00", "2024-03-09 12:00"},
+			[]string{"3", "Another User", "", "", "", "", "ano", "", "trénink", "Women", "", "2024-04-02 18:00", "2024-04-02 20:00"},
+			// Declined in one export, confirmed in a later one
+			[]string{"4", "Test User", "", "", "", "", "ne", "", "trénink", "Camp", "", "2024-03-16 10:00", "2024-03-16 12:00"},
+		)},
+		{Filename: "kemp.xlsx", Data: newExport(
+			[]string{"1", "Test User", "", "", "", "", "ano", "", "trénink", "Camp", "", "2024-03-16 10:00", "2024-03-16 12:00"},
+		)},
+	}
+
+	excelService := NewExcelService("", sheet)
+	names, months, err := excelService.ParseSourcesForNamesAndMonths(files, ParseContext{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(names) != 2 || len(months) != 2 {
+		t.Errorf("Expected the names and months of both files, got %v and %v", names, months)
+	}
+
+	tableData, _, err := excelService.ExtractTableDataWithOptions(files, ParseContext{}, "Test User", 3, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	wantOrigins := map[string]string{"2024-03-04": "muzi.xlsx", "2024-03-05": "zeny.xlsx", "2024-03-09": "muzi.xlsx", "2024-03-16": "kemp.xlsx"}
+	if len(tableData) != len(wantOrigins) {
+		t.Fatalf("Expected %d rows without the duplicate, got %+v", len(wantOrigins), tableData)
+	}
+	for _, row := range tableData {
+		if row.Origin != wantOrigins[row.Date] {
+			t.Errorf("Expected the row of %s to come from %s, got %q", row.Date, wantOrigins[row.Date], row.Origin)
+		}
+	}
+
+	diagnostics, err := excelService.Diagnose(files, ParseContext{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if diagnostics.Skipped[ExcludedDuplicate] != 2 || diagnostics.SkippedRows[0].File != "zeny.xlsx" || diagnostics.SkippedRows[0].Row != 3 || diagnostics.SkippedRows[1].Row != 5 {
+		t.Errorf("Expected rows 3 and 5 of zeny.xlsx reported as duplicates, got %+v", diagnostics.SkippedRows)
+	}
+}
+
+func TestExtractTableDataSheets(t *testing.T) {
+	f := excelize.NewFile()
+	header := []string{"ID", "Člen", "", "", "", "", "Účast potvrzena", "", "Typ události", "Název události", "", "Od", "Do"}
+	for _, sheet := range []string{"tým A", "tým B"} {
+		f.NewSheet(sheet)
+		f.SetSheetRow(sheet, "A1", &header)
+		f.SetSheetRow(sheet, "A2", &[]string{"1", "Test User", "", "", "", "", "ano", "", "trénink", sheet, "", "2024-03-04 18:00", "2024-03-04 20:00"})
+	}
+	f.SetSheetRow("tým B", "A3", &[]string{"2", "Another User", "", "", "", "", "ano", "", "trénink", "tým B", "", "2024-03-06 18:00", "2024-03-06 20:00"})
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	files := []models.SourceFile{{Filename: "export.xlsx", Data: buf.Bytes()}}
+
+	excelService := NewExcelService("", "tým *")
+	names, _, err := excelService.ParseSourcesForNamesAndMonths(files, ParseContext{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("Expected the members of both sheets, got %v", names)
+	}
+
+	// The same event on both sheets counts once, from the first sheet
+	tableData, _, err := excelService.ExtractTableDataWithOptions(files, ParseContext{}, "Test User", 3, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tableData) != 1 || tableData[0].Note != "tým A" || tableData[0].Origin != "export.xlsx tým A" {
+		t.Errorf("Expected a single row from the first sheet, got %+v", tableData)
+	}
+
+	_, _, err = excelService.ParseSourcesForNamesAndMonths(files, ParseContext{Sheets: []string{"tým B", "tým C"}})
+	if snfErr, ok := IsSheetNotFoundError(err); !ok || snfErr.SheetName != "tým C" {
+		t.Errorf("Expected the missing sheet to be reported, got %v", err)
+	}
+}
+
+func TestParseContextSessionsDoNotInterfere(t *testing.T) {
+	f := excelize.NewFile()
+	header := []string{"ID", "Člen", "", "", "", "", "Účast potvrzena", "", "Typ události", "Název události", "", "Od", "Do"}
+	for sheet, member := range map[string]string{"tým A": "Member A", "tým B": "Member B"} {
+		f.NewSheet(sheet)
+		f.SetSheetRow(sheet, "A1", &header)
+		f.SetSheetRow(sheet, "A2", &[]string{"1", member, "", "", "", "", "ano", "", "trénink", sheet, "", "2024-03-04 18:00", "2024-03-04 20:00"})
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	files := []models.SourceFile{{Filename: "export.xlsx", Data: buf.Bytes()}}
+
+	// One service serves both sessions, each reading its own sheet
+	excelService := NewExcelService("", "docházka")
+	sessions := []struct {
+		pc     ParseContext
+		member string
+	}{
+		{ParseContext{Sheets: []string{"tým A"}}, "Member A"},
+		{ParseContext{Sheets: []string{"tým B"}}, "Member B"},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 100)
+	for i := 0; i < 10; i++ {
+		for _, session := range sessions {
+			wg.Add(1)
+			go func(pc ParseContext, member string) {
+				defer wg.Done()
+				names, _, err := excelService.ParseSourcesForNamesAndMonths(files, pc)
+				if err != nil || len(names) != 1 || names[0] != member {
+					errs <- fmt.Sprintf("Expected only %s in sheets %v, got %v (%v)", member, pc.Sheets, names, err)
+					return
+				}
+				tableData, _, err := excelService.ExtractTableDataWithOptions(files, pc, member, 3, ExtractOptions{})
+				if err != nil || len(tableData) != 1 {
+					errs <- fmt.Sprintf("Expected one row of %s in sheets %v, got %+v (%v)", member, pc.Sheets, tableData, err)
+				}
+			}(session.pc, session.member)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Sessions that chose no sheet still read the configured one
+	if _, _, err := excelService.ParseSourcesForNamesAndMonths(files, ParseContext{}); err == nil {
+		t.Error("Expected the configured sheet to be missing")
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestProcessExcelFile(t *testing.T) {
+	excelService := NewExcelService("../../gorily_timesheet_template_2024.xlsx", "docházka realizačního týmu")
+	mapping := excelService.GetReportMapping()
+
+	tableData := []models.TableRow{
+		{Date: "2024-03-09", StartTime: "18:00", EndTime: "00:00", Note: "Tournament"},
+		{Date: "2024-03-10", StartTime: "00:00", EndTime: "02:30", Note: "Tournament"},
+	}
+
+	f, err := excelService.ProcessExcelFile("Novak Jan", tableData)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer f.Close()
+
+	firstname, _ := f.GetCellValue(mapping.TargetSheet, mapping.FirstNameCell)
+	if firstname != "Jan" {
+		t.Errorf("Expected firstname 'Jan', got %q", firstname)
+	}
+
+	// A midnight end time is written as 24:00, i.e. a serial value of 1
+	endTime, _ := f.GetCellValue(mapping.TargetSheet, "C7", excelize.Options{RawCellValue: true})
+	if endTime != "1" {
+		t.Errorf("Expected midnight end time serial '1', got %q", endTime)
+	}
+
+	note, _ := f.GetCellValue(mapping.TargetSheet, "F8")
+	if note != "Tournament" {
+		t.Errorf("Expected note 'Tournament', got %q", note)
+	}
+
+	// The template computes hours with formulas, which must be preserved
+	formula, _ := f.GetCellFormula(mapping.TargetSheet, mapping.TotalHoursCell)
+	if formula == "" {
+		t.Errorf("Expected total hours formula in %s to be preserved", mapping.TotalHoursCell)
+	}
+
+	totals := ComputeTotals(tableData)
+	if totals.TotalHours != 8.5 {
+		t.Errorf("Expected 8.5 total hours, got %v", totals.TotalHours)
+	}
+}
+
+func TestProcessExcelFileOverflow(t *testing.T) {
+	excelService := NewExcelService("../../gorily_timesheet_template_2024.xlsx", "docházka realizačního týmu")
+	mapping := excelService.GetReportMapping()
+
+	var tableData []models.TableRow
+	for i := 0; i < mapping.MaxRows+5; i++ {
+		tableData = append(tableData, models.TableRow{
+			Date:      "2024-03-01",
+			StartTime: "10:00",
+			EndTime:   "11:00",
+			Note:      fmt.Sprintf("Entry %d", i+1),
+		})
+	}
+
+	f, err := excelService.ProcessExcelFile("Novak Jan", tableData)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer f.Close()
+
+	sheets := excelService.ReportSheetNames(tableData)
+	if len(sheets) != 2 {
+		t.Fatalf("Expected 2 report sheets, got %d", len(sheets))
+	}
+
+	// The continuation sheet keeps the name header and holds the remaining rows
+	lastname, _ := f.GetCellValue(sheets[1], mapping.LastNameCell)
+	if lastname != "Novak" {
+		t.Errorf("Expected lastname 'Novak' on continuation sheet, got %q", lastname)
+	}
+
+	note, _ := f.GetCellValue(sheets[1], fmt.Sprintf("%s%d", mapping.NoteColumn, mapping.StartRow))
+	if want := fmt.Sprintf("Entry %d", mapping.MaxRows+1); note != want {
+		t.Errorf("Expected first continuation note %q, got %q", want, note)
+	}
+}
+
+func TestExtractReportData(t *testing.T) {
+	excelService := NewExcelService("../../gorily_timesheet_template_2024.xlsx", "docházka realizačního týmu")
+	mapping := excelService.GetReportMapping()
+
+	tableData := []models.TableRow{
+		{Date: "2024-03-09", StartTime: "18:00", EndTime: "00:00", Note: "Tournament"},
+	}
+	for i := 0; i < mapping.MaxRows; i++ {
+		tableData = append(tableData, models.TableRow{
+			Date:      fmt.Sprintf("2024-03-%02d", i%28+1),
+			StartTime: "10:00",
+			EndTime:   "11:30",
+			Note:      fmt.Sprintf("Entry %d", i+1),
+		})
+	}
+
+	f, err := excelService.ProcessExcelFile("Novák Jan", tableData)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	buf, err := f.WriteToBuffer()
+	f.Close()
+	if err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+
+	if !excelService.IsGeneratedReport(buf.Bytes()) {
+		t.Fatal("Expected generated report to be recognized")
+	}
+	if excelService.IsGeneratedReport(testutil.CreateTestExcelFile(t)) {
+		t.Error("Expected attendance export not to be recognized as a report")
+	}
+
+	name, rows, err := excelService.ExtractReportData(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if name != "Novák Jan" {
+		t.Errorf("Expected name 'Novák Jan', got %q", name)
+	}
+	if len(rows) != len(tableData) {
+		t.Fatalf("Expected %d rows, got %d", len(tableData), len(rows))
+	}
+	for i := range tableData {
+		if rows[i] != tableData[i] {
+			t.Errorf("Row %d: expected %+v, got %+v", i, tableData[i], rows[i])
+		}
+	}
+}
+
+func TestStampApproval(t *testing.T) {
+	excelService := NewExcelService("../../gorily_timesheet_template_2024.xlsx", "docházka realizačního týmu")
+	mapping := excelService.GetReportMapping()
+
+	// Enough entries for a continuation sheet
+	var tableData []models.TableRow
+	for i := 0; i <= mapping.MaxRows; i++ {
+		tableData = append(tableData, models.TableRow{Date: "2024-03-09", StartTime: "18:00", EndTime: "20:00", Note: "Practice"})
+	}
+	f, err := excelService.ProcessExcelFile("Novák Jan", tableData)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer f.Close()
+
+	approvedAt := time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC)
+	labels := ApprovalLabels{ApprovedBy: "Approved by:", ApprovedAt: "Approval date:"}
+	if err := excelService.StampApproval(f, tableData, "Eva Koordinátorová", approvedAt, labels); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sheets := excelService.ReportSheetNames(tableData)
+	if len(sheets) != 2 {
+		t.Fatalf("Expected 2 report sheets, got %d", len(sheets))
+	}
+	for _, sheet := range sheets {
+		approver, _ := f.GetCellValue(sheet, mapping.ApprovedByCell)
+		if approver != "Eva Koordinátorová" {
+			t.Errorf("Expected approver 'Eva Koordinátorová' on %q, got %q", sheet, approver)
+		}
+		date, _ := f.GetCellValue(sheet, mapping.ApprovedAtCell)
+		if date != "02.04.2024" {
+			t.Errorf("Expected approval date '02.04.2024' on %q, got %q", sheet, date)
+		}
+		label, _ := f.GetCellValue(sheet, mapping.ApprovedByLabelCell)
+		if label != labels.ApprovedBy {
+			t.Errorf("Expected label %q on %q, got %q", labels.ApprovedBy, sheet, label)
+		}
+	}
+}
+
+func TestProcessExcelFileHolidays(t *testing.T) {
+	calendar, err := holidays.New("CZ", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	excelService := NewExcelService("../../gorily_timesheet_template_2024.xlsx", "docházka realizačního týmu", WithHolidayCalendar(calendar))
+	mapping := excelService.GetReportMapping()
+
+	f, err := excelService.ProcessExcelFile("Novák Jan", []models.TableRow{
+		{Date: "2024-03-28", StartTime: "18:00", EndTime: "20:00"}, // Thursday
+		{Date: "2024-03-29", StartTime: "18:00", EndTime: "20:00"}, // Good Friday
+		{Date: "2024-03-30", StartTime: "10:00", EndTime: "12:00"}, // Saturday
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer f.Close()
+
+	workdayStyle, _ := f.GetCellStyle(mapping.TargetSheet, fmt.Sprintf("%s%d", mapping.DateColumn, mapping.StartRow))
+	for i, want := range []struct {
+		holiday string
+		dayOff  bool
+	}{
+		{"ne", false},
+		{"ano", true},
+		{"ne", true},
+	} {
+		row := mapping.StartRow + i
+		holiday, _ := f.GetCellValue(mapping.TargetSheet, fmt.Sprintf("%s%d", mapping.HolidayColumn, row))
+		if holiday != want.holiday {
+			t.Errorf("Row %d: expected holiday %q, got %q", i, want.holiday, holiday)
+		}
+		style, _ := f.GetCellStyle(mapping.TargetSheet, fmt.Sprintf("%s%d", mapping.DateColumn, row))
+		if (style != workdayStyle) != want.dayOff {
+			t.Errorf("Row %d: expected day off shading %t, got style %d (workday %d)", i, want.dayOff, style, workdayStyle)
+		}
+	}
+}
+
+func TestReportSheetNamesWithoutRows(t *testing.T) {
+	mapping := DefaultReportMapping()
+	mapping.MaxRows = 0
+
+	if sheets := reportSheetNames(mapping, 3); len(sheets) != 1 || sheets[0] != mapping.TargetSheet {
+		t.Errorf("Expected only the target sheet, got %v", sheets)
+	}
+}
@@ -36,17 +36,27 @@ func NewFileStore(expiryTime time.Duration, cleanupInterval time.Duration) *File
 }
 
 func (fs *FileStore) StoreFileData(data []byte, names []string, months []string, sheetName string) string {
+	entry := models.FileData{
+		Data:   data,
+		Kind:   models.SourceKindExcel,
+		Names:  names,
+		Months: months,
+	}
+	if sheetName != "" {
+		entry.SheetNames = []string{sheetName}
+	}
+	return fs.StoreFileDataEntry(entry)
+}
+
+// StoreFileDataEntry stores an uploaded source of any kind and returns its token
+func (fs *FileStore) StoreFileDataEntry(entry models.FileData) string {
 	startTime := time.Now()
 	token := utils.GenerateFileToken()
 
+	entry.Timestamp = time.Now()
+
 	fs.fileMutex.Lock()
-	fs.fileData[token] = models.FileData{
-		Data:      data,
-		Names:     names,
-		Months:    months,
-		SheetName: sheetName,
-		Timestamp: time.Now(),
-	}
+	fs.fileData[token] = entry
 	fs.fileMutex.Unlock()
 
 	m := metrics.GetMetrics()
@@ -56,6 +66,21 @@ func (fs *FileStore) StoreFileData(data []byte, names []string, months []string,
 	return token
 }
 
+// UpdateFileDataEntry replaces the source stored under an existing token,
+// e.g. after more files were added to it, and renews its expiry
+func (fs *FileStore) UpdateFileDataEntry(token string, entry models.FileData) bool {
+	entry.Timestamp = time.Now()
+
+	fs.fileMutex.Lock()
+	defer fs.fileMutex.Unlock()
+
+	if _, ok := fs.fileData[token]; !ok {
+		return false
+	}
+	fs.fileData[token] = entry
+	return true
+}
+
 func (fs *FileStore) GetFileData(token string) (models.FileData, bool) {
 	fs.fileMutex.RLock()
 	data, ok := fs.fileData[token]
@@ -3,6 +3,8 @@ package services
 import (
 	"testing"
 	"time"
+
+	"timesheet-filler/internal/models"
 )
 
 func TestFileStore(t *testing.T) {
@@ -81,3 +83,27 @@ func TestFileStore(t *testing.T) {
 		t.Error("Data should have expired")
 	}
 }
+
+func TestFileStoreUpdateFileDataEntry(t *testing.T) {
+	fileStore := NewFileStore(time.Hour, time.Hour)
+
+	token := fileStore.StoreFileData([]byte("a"), []string{"Name1"}, []string{"1"}, "")
+	updated := models.FileData{
+		Data:  []byte("a"),
+		Files: []models.SourceFile{{Filename: "a.xlsx", Data: []byte("a")}, {Filename: "b.xlsx", Data: []byte("b")}},
+		Kind:  models.SourceKindExcel,
+		Names: []string{"Name1", "Name2"},
+	}
+	if !fileStore.UpdateFileDataEntry(token, updated) {
+		t.Fatal("Expected the stored entry to be updated")
+	}
+
+	data, ok := fileStore.GetFileData(token)
+	if !ok || len(data.Files) != 2 || len(data.Names) != 2 {
+		t.Errorf("Expected the updated entry with 2 files, got %+v", data)
+	}
+
+	if fileStore.UpdateFileDataEntry("unknown", updated) {
+		t.Error("Expected no update for an unknown token")
+	}
+}
@@ -0,0 +1,498 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+// ICalService converts timesheet rows to and from iCalendar (.ics) files
+type ICalService struct {
+	location     *time.Location
+	splitOptions EventSplitOptions
+}
+
+// NewICalService creates an iCalendar converter. Floating times in imported
+// calendars and all exported times are interpreted in location.
+func NewICalService(location *time.Location, splitOptions EventSplitOptions) *ICalService {
+	if location == nil {
+		location = time.Local
+	}
+	return &ICalService{
+		location:     location,
+		splitOptions: splitOptions,
+	}
+}
+
+// IsICalendar reports whether data looks like an iCalendar file
+func IsICalendar(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), []byte("BEGIN:VCALENDAR"))
+}
+
+// Encode writes the rows of a member as an iCalendar file with one event per row
+func (is *ICalService) Encode(name string, tableData []models.TableRow) []byte {
+	var b strings.Builder
+	writeLine := func(line string) {
+		b.WriteString(foldICalLine(line))
+		b.WriteString("\r\n")
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//Timesheet Filler//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("X-WR-CALNAME:" + escapeICalText(name))
+
+	for i, row := range tableData {
+		date, err := time.Parse("2006-01-02", row.Date)
+		if err != nil {
+			continue
+		}
+		start, end, err := utils.ParseTimeRange(row.StartTime, row.EndTime)
+		if err != nil || !end.After(start) {
+			continue
+		}
+
+		// Build both ends from wall clock minutes so that days with a DST
+		// change keep the times shown in the timesheet
+		startMinutes := start.Hour()*60 + start.Minute()
+		endMinutes := startMinutes + int(end.Sub(start).Minutes())
+		startAt := time.Date(date.Year(), date.Month(), date.Day(), 0, startMinutes, 0, 0, is.location)
+		endAt := time.Date(date.Year(), date.Month(), date.Day(), 0, endMinutes, 0, 0, is.location)
+
+		writeLine("BEGIN:VEVENT")
+		writeLine(fmt.Sprintf("UID:%s-%d-%s@timesheet-filler", startAt.UTC().Format("20060102T150405Z"), i, utils.RemoveDiacritics(strings.ReplaceAll(name, " ", "-"))))
+		writeLine("DTSTAMP:" + stamp)
+		writeLine("DTSTART:" + startAt.UTC().Format("20060102T150405Z"))
+		writeLine("DTEND:" + endAt.UTC().Format("20060102T150405Z"))
+		writeLine("SUMMARY:" + escapeICalText(row.Note))
+		writeLine("END:VEVENT")
+	}
+
+	writeLine("END:VCALENDAR")
+
+	return []byte(b.String())
+}
+
+// Reasons calendar events are left out, next to the reasons of exports
+const (
+	ExcludedAllDay     = "all_day"
+	ExcludedRecurrence = "recurrence"
+)
+
+const (
+	// Repeating events without an end are expanded this many years ahead
+	icalRecurrenceYears = 2
+	// Upper bound of the occurrences of a single repeating event
+	icalMaxOccurrences = 1000
+)
+
+// icalEvent holds the properties of a VEVENT that are read, times as
+// "params:value"
+type icalEvent struct {
+	uid          string
+	summary      string
+	dtStart      string
+	dtEnd        string
+	duration     string
+	rrule        string
+	exDates      []string
+	recurrenceID string
+}
+
+// Parse reads the events of an iCalendar file into timesheet rows, using each
+// event summary as the note. Repeating events are expanded, events crossing
+// midnight are split into per-day rows and the result is sorted by date and
+// time. Occurrences moved by an event with a RECURRENCE-ID are replaced by
+// that event. It also returns how many events were left out, by reason: all-day
+// events, which have no working hours, and events repeating by rules that
+// cannot be read.
+func (is *ICalService) Parse(data []byte) ([]models.TableRow, map[string]int, error) {
+	if !IsICalendar(data) {
+		return nil, nil, fmt.Errorf("file is not an iCalendar file")
+	}
+
+	var events []icalEvent
+	var inEvent bool
+	var event icalEvent
+
+	for _, line := range unfoldICalLines(data) {
+		name, params, value := splitICalProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			event = icalEvent{}
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			events = append(events, event)
+		case !inEvent:
+			continue
+		case name == "UID":
+			event.uid = value
+		case name == "SUMMARY":
+			event.summary = unescapeICalText(value)
+		case name == "DTSTART":
+			event.dtStart = params + ":" + value
+		case name == "DTEND":
+			event.dtEnd = params + ":" + value
+		case name == "DURATION":
+			event.duration = value
+		case name == "RRULE":
+			event.rrule = value
+		case name == "EXDATE":
+			for _, date := range strings.Split(value, ",") {
+				event.exDates = append(event.exDates, params+":"+date)
+			}
+		case name == "RECURRENCE-ID":
+			event.recurrenceID = params + ":" + value
+		}
+	}
+
+	// Occurrences of a repeating event that were moved or changed, by UID
+	overridden := make(map[string][]string)
+	for _, event := range events {
+		if event.recurrenceID != "" {
+			overridden[event.uid] = append(overridden[event.uid], event.recurrenceID)
+		}
+	}
+
+	var tableData []models.TableRow
+	skipped := make(map[string]int)
+	for _, event := range events {
+		if event.rrule != "" && event.recurrenceID == "" {
+			event.exDates = append(event.exDates, overridden[event.uid]...)
+		}
+		rows, reason := is.eventRows(event)
+		if reason != "" {
+			skipped[reason]++
+			continue
+		}
+		tableData = append(tableData, rows...)
+	}
+
+	sort.SliceStable(tableData, func(i, j int) bool {
+		if tableData[i].Date != tableData[j].Date {
+			return tableData[i].Date < tableData[j].Date
+		}
+		return tableData[i].StartTime < tableData[j].StartTime
+	})
+
+	return tableData, skipped, nil
+}
+
+// eventRows returns the rows of all occurrences of an event, or the reason
+// the event is left out
+func (is *ICalService) eventRows(event icalEvent) ([]models.TableRow, string) {
+	if isICalDate(event.dtStart) {
+		return nil, ExcludedAllDay
+	}
+	start, err := is.parseICalTime(event.dtStart)
+	if err != nil {
+		return nil, ExcludedInvalidDate
+	}
+	end, err := is.parseICalTime(event.dtEnd)
+	if err != nil {
+		end = start
+		if duration, err := parseICalDuration(event.duration); err == nil {
+			end = start.Add(duration)
+		}
+	}
+
+	starts := []time.Time{start}
+	if event.rrule != "" {
+		if starts, err = is.expandRecurrence(start, event.rrule); err != nil {
+			return nil, ExcludedRecurrence
+		}
+	}
+
+	exceptions := make(map[time.Time]bool)
+	for _, exDate := range event.exDates {
+		if t, err := is.parseICalTime(exDate); err == nil {
+			exceptions[t] = true
+		}
+	}
+
+	var rows []models.TableRow
+	for _, occurrence := range starts {
+		if exceptions[occurrence] {
+			continue
+		}
+		rows = append(rows, SplitEvent(occurrence, occurrence.Add(end.Sub(start)), event.summary, is.splitOptions)...)
+	}
+	return rows, ""
+}
+
+// expandRecurrence returns the starts of a repeating event. Daily, weekly
+// (optionally on given weekdays), monthly and yearly rules with an interval,
+// count or end are understood; other rules return an error.
+func (is *ICalService) expandRecurrence(start time.Time, rule string) ([]time.Time, error) {
+	freq := ""
+	interval, count := 1, 0
+	var until time.Time
+	var weekdays []time.Weekday
+
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq = strings.ToUpper(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid interval %q", value)
+			}
+			interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count %q", value)
+			}
+			count = n
+		case "UNTIL":
+			t, err := is.parseICalTime(":" + value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end %q", value)
+			}
+			// An end date includes the whole day
+			if len(value) == len("20060102") {
+				t = t.AddDate(0, 0, 1).Add(-time.Minute)
+			}
+			until = t
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				weekday, ok := icalWeekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("unsupported weekday %q", day)
+				}
+				weekdays = append(weekdays, weekday)
+			}
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+	if len(weekdays) > 0 && freq != "WEEKLY" {
+		return nil, fmt.Errorf("weekdays are only supported for weekly rules")
+	}
+	if until.IsZero() && count == 0 {
+		until = start.AddDate(icalRecurrenceYears, 0, 0)
+	}
+
+	// Candidate starts of the n-th period; months too short for the day of
+	// the first occurrence are skipped
+	next := func(n int) []time.Time {
+		switch freq {
+		case "DAILY":
+			return []time.Time{start.AddDate(0, 0, n*interval)}
+		case "WEEKLY":
+			if len(weekdays) == 0 {
+				return []time.Time{start.AddDate(0, 0, 7*n*interval)}
+			}
+			// Weeks start on Monday
+			monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*n*interval)
+			var starts []time.Time
+			for _, weekday := range weekdays {
+				starts = append(starts, monday.AddDate(0, 0, (int(weekday)+6)%7))
+			}
+			sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+			return starts
+		case "MONTHLY", "YEARLY":
+			months := n * interval
+			if freq == "YEARLY" {
+				months *= 12
+			}
+			t := start.AddDate(0, months, 0)
+			if t.Day() != start.Day() {
+				return nil
+			}
+			return []time.Time{t}
+		}
+		return nil
+	}
+	if next(0) == nil {
+		return nil, fmt.Errorf("unsupported frequency %q", freq)
+	}
+
+	var starts []time.Time
+	for n := 0; n < icalMaxOccurrences; n++ {
+		for _, t := range next(n) {
+			if !until.IsZero() && t.After(until) {
+				return starts, nil
+			}
+			if t.Before(start) {
+				continue
+			}
+			starts = append(starts, t)
+			if len(starts) == count || len(starts) == icalMaxOccurrences {
+				return starts, nil
+			}
+		}
+	}
+	return starts, nil
+}
+
+// parseICalDuration parses an RFC 5545 duration such as "PT1H30M" or "P1D".
+// Days and weeks are counted as 24 hours, which keeps the wall clock time
+// since event times are floating.
+func parseICalDuration(value string) (time.Duration, error) {
+	// Events cannot last a negative time, so a leading "-" is not accepted
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(value, "+"), "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+	}
+	var duration time.Duration
+	inTime, found := false, false
+	number := ""
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T' && !inTime && number == "":
+			inTime = true
+		default:
+			unit, ok := units[c]
+			// Hours, minutes and seconds follow the T, days and weeks precede it
+			if !ok || number == "" || inTime != (c == 'H' || c == 'M' || c == 'S') {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			n, _ := strconv.Atoi(number)
+			duration += time.Duration(n) * unit
+			number = ""
+			found = true
+		}
+	}
+	if number != "" || !found {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+// RFC 5545 weekday codes
+var icalWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// isICalDate reports whether a DTSTART stored as "params:value" is a date
+// without a time, as used by all-day events
+func isICalDate(raw string) bool {
+	params, value, _ := strings.Cut(raw, ":")
+	return strings.Contains(strings.ToUpper(params), "VALUE=DATE") && !strings.Contains(strings.ToUpper(params), "VALUE=DATE-TIME") ||
+		len(value) == len("20060102")
+}
+
+// parseICalTime parses a DTSTART/DTEND value stored as "params:value". UTC
+// times are converted to the service location, TZID parameters are honoured
+// when the zone is known, and floating times use the service location.
+func (is *ICalService) parseICalTime(raw string) (time.Time, error) {
+	params, value, _ := strings.Cut(raw, ":")
+	if value == "" {
+		return time.Time{}, fmt.Errorf("missing time value")
+	}
+
+	location := is.location
+	for _, param := range strings.Split(params, ";") {
+		if tzid, ok := strings.CutPrefix(param, "TZID="); ok {
+			if loc, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+				location = loc
+			}
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return is.toFloating(t.In(is.location)), nil
+	case len(value) == len("20060102"):
+		t, err := time.ParseInLocation("20060102", value, location)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return is.toFloating(t), nil
+	default:
+		t, err := time.ParseInLocation("20060102T150405", value, location)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return is.toFloating(t.In(is.location)), nil
+	}
+}
+
+// toFloating drops the zone from t so that rows are built from the wall clock
+// time in the service location.
+func (is *ICalService) toFloating(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// unfoldICalLines joins folded continuation lines as described in RFC 5545
+func unfoldICalLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitICalProperty splits "NAME;PARAM=x:value" into its parts
+func splitICalProperty(line string) (name, params, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	name, params, _ = strings.Cut(head, ";")
+	return strings.ToUpper(name), params, value
+}
+
+// foldICalLine splits lines longer than 75 octets without breaking UTF-8 runes
+func foldICalLine(line string) string {
+	var b strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > 75 {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	return b.String()
+}
+
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func unescapeICalText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"timesheet-filler/internal/models"
+)
+
+func TestICalRoundTrip(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	icalService := NewICalService(location, EventSplitOptions{})
+
+	rows := []models.TableRow{
+		{Date: "2024-03-09", StartTime: "18:00", EndTime: "20:00", Note: "Practice, hall B"},
+		{Date: "2024-03-31", StartTime: "22:00", EndTime: "00:00", Note: "Tournament"},
+	}
+
+	data := icalService.Encode("Jan Novák", rows)
+	if !IsICalendar(data) {
+		t.Fatalf("Encode() output is not recognised as iCalendar")
+	}
+	if !strings.Contains(string(data), "DTSTART:20240309T170000Z") {
+		t.Errorf("expected UTC start time in output, got:\n%s", data)
+	}
+
+	got, _, err := icalService.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(got) != len(rows) {
+		t.Fatalf("Parse() returned %d rows (%v), want %d", len(got), got, len(rows))
+	}
+	for i := range rows {
+		if got[i] != rows[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, got[i], rows[i])
+		}
+	}
+}
+
+func TestICalParse(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	icalService := NewICalService(location, EventSplitOptions{})
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Europe/London:20240310T090000",
+		"DTEND;TZID=Europe/London:20240310T100000",
+		"SUMMARY:Coaching\\, U12",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20240309T200000",
+		"DTEND:20240310T020000",
+		"SUMMARY:Night game with a long description that is folded over more th",
+		" an one line",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	got, _, err := icalService.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	want := []models.TableRow{
+		{Date: "2024-03-09", StartTime: "20:00", EndTime: "00:00", Note: "Night game with a long description that is folded over more than one line"},
+		{Date: "2024-03-10", StartTime: "00:00", EndTime: "02:00", Note: "Night game with a long description that is folded over more than one line"},
+		{Date: "2024-03-10", StartTime: "10:00", EndTime: "11:00", Note: "Coaching, U12"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parse() returned %d rows (%v), want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if _, _, err := icalService.Parse([]byte("not a calendar")); err == nil {
+		t.Errorf("Parse() accepted a non-calendar file")
+	}
+}
+
+func TestICalParseRecurrence(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	icalService := NewICalService(location, EventSplitOptions{})
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Europe/Prague:20240304T170000",
+		"DTEND;TZID=Europe/Prague:20240304T183000",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,TH;UNTIL=20240314T235959Z",
+		"EXDATE;TZID=Europe/Prague:20240307T170000",
+		"SUMMARY:Practice",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Europe/Prague:20240330T100000",
+		"DTEND;TZID=Europe/Prague:20240330T120000",
+		"RRULE:FREQ=DAILY;COUNT=2",
+		"SUMMARY:Camp",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20240316",
+		"DTEND;VALUE=DATE:20240317",
+		"SUMMARY:Tournament",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20240320T100000",
+		"DTEND:20240320T110000",
+		"RRULE:FREQ=MONTHLY;BYSETPOS=-1;BYDAY=MO",
+		"SUMMARY:Meeting",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	got, skipped, err := icalService.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	// The camp repeats into summer time at the same local time
+	want := []models.TableRow{
+		{Date: "2024-03-04", StartTime: "17:00", EndTime: "18:30", Note: "Practice"},
+		{Date: "2024-03-11", StartTime: "17:00", EndTime: "18:30", Note: "Practice"},
+		{Date: "2024-03-14", StartTime: "17:00", EndTime: "18:30", Note: "Practice"},
+		{Date: "2024-03-30", StartTime: "10:00", EndTime: "12:00", Note: "Camp"},
+		{Date: "2024-03-31", StartTime: "10:00", EndTime: "12:00", Note: "Camp"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parse() returned %d rows (%v), want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if skipped[ExcludedAllDay] != 1 || skipped[ExcludedRecurrence] != 1 {
+		t.Errorf("Parse() skipped %v, want one all-day and one unreadable repeating event", skipped)
+	}
+}
+
+func TestICalParseDurationAndOverrides(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	icalService := NewICalService(location, EventSplitOptions{})
+
+	// The override of the second practice comes before its series and moves
+	// it to the next day
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:practice@example.com",
+		"RECURRENCE-ID;TZID=Europe/Prague:20240311T170000",
+		"DTSTART;TZID=Europe/Prague:20240312T180000",
+		"DTEND;TZID=Europe/Prague:20240312T193000",
+		"SUMMARY:Practice",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:practice@example.com",
+		"DTSTART;TZID=Europe/Prague:20240304T170000",
+		"DURATION:PT1H30M",
+		"RRULE:FREQ=WEEKLY;COUNT=3",
+		"SUMMARY:Practice",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:match@example.com",
+		"DTSTART:20240316T220000",
+		"DURATION:P1DT2H",
+		"SUMMARY:Tournament",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	got, _, err := icalService.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	// Without DTEND the duration sets the end, also across midnight
+	want := []models.TableRow{
+		{Date: "2024-03-04", StartTime: "17:00", EndTime: "18:30", Note: "Practice"},
+		{Date: "2024-03-12", StartTime: "18:00", EndTime: "19:30", Note: "Practice"},
+		{Date: "2024-03-16", StartTime: "22:00", EndTime: "00:00", Note: "Tournament"},
+		{Date: "2024-03-17", StartTime: "00:00", EndTime: "00:00", Note: "Tournament"},
+		{Date: "2024-03-18", StartTime: "17:00", EndTime: "18:30", Note: "Practice"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parse() returned %d rows (%v), want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseICalDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"PT1H30M", 90 * time.Minute},
+		{"P1D", 24 * time.Hour},
+		{"+P1W", 7 * 24 * time.Hour},
+		{"P1DT2H15S", 26*time.Hour + 15*time.Second},
+	}
+	for _, tt := range tests {
+		got, err := parseICalDuration(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseICalDuration(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "P", "PT", "-PT1H", "PT1D", "P1H", "P1", "1H"} {
+		if _, err := parseICalDuration(value); err == nil {
+			t.Errorf("parseICalDuration(%q) succeeded, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"timesheet-filler/internal/models"
+)
+
+var (
+	ErrLinkInvalid = errors.New("personal link is invalid")
+	ErrLinkExpired = errors.New("personal link has expired")
+)
+
+// PersonalLinkService issues links that open a member's own timesheet for
+// one month. The member, month and expiry are carried in the link and signed
+// with HMAC-SHA256, so links need not be stored to be checked.
+type PersonalLinkService struct {
+	secret    []byte
+	expiry    time.Duration
+	publicURL string
+}
+
+// linkPayload is the signed part of a link token
+type linkPayload struct {
+	Name      string `json:"n"`
+	Year      int    `json:"y"`
+	Month     int    `json:"m"`
+	ExpiresAt int64  `json:"e"`
+}
+
+func NewPersonalLinkService(secret []byte, expiry time.Duration, publicURL string) *PersonalLinkService {
+	return &PersonalLinkService{
+		secret:    secret,
+		expiry:    expiry,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// Issue creates the link of a member for a month, valid for the configured
+// expiry from now
+func (ls *PersonalLinkService) Issue(name string, year, month int, now time.Time) models.PersonalLink {
+	expiresAt := now.Add(ls.expiry).Truncate(time.Second)
+	payload, _ := json.Marshal(linkPayload{
+		Name:      name,
+		Year:      year,
+		Month:     month,
+		ExpiresAt: expiresAt.Unix(),
+	})
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	token := encoded + "." + base64.RawURLEncoding.EncodeToString(ls.sign(encoded))
+
+	return models.PersonalLink{
+		Name:      name,
+		Year:      year,
+		Month:     month,
+		ExpiresAt: expiresAt,
+		URL:       ls.publicURL + "/me?token=" + token,
+	}
+}
+
+// Verify checks the signature and expiry of a link token and returns the
+// member and month it opens
+func (ls *PersonalLinkService) Verify(token string, now time.Time) (models.PersonalLink, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return models.PersonalLink{}, ErrLinkInvalid
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sum, ls.sign(encoded)) {
+		return models.PersonalLink{}, ErrLinkInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return models.PersonalLink{}, ErrLinkInvalid
+	}
+	var payload linkPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Name == "" || payload.Month < 1 || payload.Month > 12 {
+		return models.PersonalLink{}, ErrLinkInvalid
+	}
+
+	link := models.PersonalLink{
+		Name:      payload.Name,
+		Year:      payload.Year,
+		Month:     payload.Month,
+		ExpiresAt: time.Unix(payload.ExpiresAt, 0),
+	}
+	if !now.Before(link.ExpiresAt) {
+		return link, ErrLinkExpired
+	}
+	return link, nil
+}
+
+func (ls *PersonalLinkService) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, ls.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
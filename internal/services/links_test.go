@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPersonalLinkService(t *testing.T) {
+	links := NewPersonalLinkService([]byte("secret"), 24*time.Hour, "https://timesheets.example.com/")
+	now := time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC)
+
+	link := links.Issue("Nováková Jana", 2024, 3, now)
+	if !strings.HasPrefix(link.URL, "https://timesheets.example.com/me?token=") {
+		t.Fatalf("Expected a link to the personal page, got %q", link.URL)
+	}
+	parsed, err := url.Parse(link.URL)
+	if err != nil {
+		t.Fatalf("Expected a valid URL, got %v", err)
+	}
+	token := parsed.Query().Get("token")
+
+	verified, err := links.Verify(token, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if verified.Name != "Nováková Jana" || verified.Year != 2024 || verified.Month != 3 || !verified.ExpiresAt.Equal(now.Add(24*time.Hour)) {
+		t.Errorf("Expected the member and month of the link, got %+v", verified)
+	}
+
+	if _, err := links.Verify(token, now.Add(24*time.Hour)); !errors.Is(err, ErrLinkExpired) {
+		t.Errorf("Expected the link to expire, got %v", err)
+	}
+
+	// Links of another secret, for instance of another tenant, are rejected
+	other := NewPersonalLinkService([]byte("other"), 24*time.Hour, "")
+	if _, err := other.Verify(token, now); !errors.Is(err, ErrLinkInvalid) {
+		t.Errorf("Expected a link signed with another secret to be rejected, got %v", err)
+	}
+
+	// Changing the member in the payload breaks the signature
+	payload, signature, _ := strings.Cut(token, ".")
+	forged := links.Issue("Dvořák Petr", 2024, 3, now)
+	forgedPayload, _, _ := strings.Cut(strings.TrimPrefix(forged.URL, "https://timesheets.example.com/me?token="), ".")
+	for _, token := range []string{"", "garbage", payload, forgedPayload + "." + signature, payload + ".!"} {
+		if _, err := links.Verify(token, now); !errors.Is(err, ErrLinkInvalid) {
+			t.Errorf("Expected %q to be rejected, got %v", token, err)
+		}
+	}
+}
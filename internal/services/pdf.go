@@ -0,0 +1,296 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"timesheet-filler/internal/metrics"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+// PDF page geometry in points (A4 portrait)
+const (
+	pdfPageWidth   = 595
+	pdfPageHeight  = 842
+	pdfMarginLeft  = 50
+	pdfMarginRight = 50
+	pdfRowHeight   = 16
+)
+
+// pdfGlyphs maps the non-ASCII characters used in Czech and Slovak names and
+// notes to glyph names of the standard Helvetica font. They are assigned byte
+// codes from 128 upwards through the font encoding's Differences array.
+var pdfGlyphs = []struct {
+	r     rune
+	glyph string
+}{
+	{'á', "aacute"}, {'Á', "Aacute"}, {'č', "ccaron"}, {'Č', "Ccaron"},
+	{'ď', "dcaron"}, {'Ď', "Dcaron"}, {'é', "eacute"}, {'É', "Eacute"},
+	{'ě', "ecaron"}, {'Ě', "Ecaron"}, {'í', "iacute"}, {'Í', "Iacute"},
+	{'ň', "ncaron"}, {'Ň', "Ncaron"}, {'ó', "oacute"}, {'Ó', "Oacute"},
+	{'ř', "rcaron"}, {'Ř', "Rcaron"}, {'š', "scaron"}, {'Š', "Scaron"},
+	{'ť', "tcaron"}, {'Ť', "Tcaron"}, {'ú', "uacute"}, {'Ú', "Uacute"},
+	{'ů', "uring"}, {'Ů', "Uring"}, {'ý', "yacute"}, {'Ý', "Yacute"},
+	{'ž', "zcaron"}, {'Ž', "Zcaron"}, {'ä', "adieresis"}, {'ö', "odieresis"},
+	{'ü', "udieresis"}, {'ô', "ocircumflex"}, {'ľ', "lcaron"}, {'Ľ', "Lcaron"},
+	{'ĺ', "lacute"}, {'ŕ', "racute"}, {'ß', "germandbls"}, {'–', "endash"},
+	{'„', "quotedblbase"}, {'“', "quotedblleft"},
+}
+
+// PDFLabels holds the translated texts printed on the PDF timesheet
+type PDFLabels struct {
+	Title     string
+	Name      string
+	Period    string
+	Date      string
+	StartTime string
+	EndTime   string
+	Hours     string
+	Note      string
+	Total     string
+	Signature string
+	Page      string
+}
+
+// PDFReport is the content of a PDF timesheet
+type PDFReport struct {
+	Name string
+	// Names from the member's profile, split from Name when both are empty
+	FirstName string
+	LastName  string
+	Period    string
+	TableData []models.TableRow
+	Labels    PDFLabels
+}
+
+type PDFService struct {
+	mappingFor ReportMappingFunc
+}
+
+// NewPDFService creates a PDF renderer that paginates entries the same way as
+// the report template of the report period, as returned by mappingFor.
+func NewPDFService(mappingFor ReportMappingFunc) *PDFService {
+	return &PDFService{
+		mappingFor: mappingFor,
+	}
+}
+
+// RenderTimesheet renders a printable timesheet with a header, the entries
+// table, totals and a signature line.
+func (ps *PDFService) RenderTimesheet(report PDFReport) ([]byte, error) {
+	startTime := time.Now()
+
+	rowsPerPage := ps.mappingFor(report.TableData).MaxRows
+	if rowsPerPage <= 0 {
+		rowsPerPage = MaxReportRows
+	}
+
+	pageCount := (len(report.TableData) + rowsPerPage - 1) / rowsPerPage
+	if pageCount == 0 {
+		pageCount = 1
+	}
+
+	firstname, lastname := report.FirstName, report.LastName
+	if firstname == "" && lastname == "" {
+		firstname, lastname = utils.SplitName(report.Name)
+	}
+	totals := ComputeTotals(report.TableData)
+	labels := report.Labels
+
+	doc := newPDFDocument()
+	for page := 0; page < pageCount; page++ {
+		c := &pdfContent{}
+
+		// Header
+		y := float64(pdfPageHeight - 60)
+		c.text(pdfMarginLeft, y, 18, true, labels.Title)
+		y -= 28
+		c.text(pdfMarginLeft, y, 11, true, labels.Name+":")
+		c.text(pdfMarginLeft+80, y, 11, false, strings.TrimSpace(firstname+" "+lastname))
+		y -= 16
+		c.text(pdfMarginLeft, y, 11, true, labels.Period+":")
+		c.text(pdfMarginLeft+80, y, 11, false, report.Period)
+		c.text(pdfPageWidth-pdfMarginRight-60, y, 9, false, fmt.Sprintf("%s %d/%d", labels.Page, page+1, pageCount))
+
+		// Entries table
+		columns := []float64{pdfMarginLeft, pdfMarginLeft + 80, pdfMarginLeft + 140, pdfMarginLeft + 200, pdfMarginLeft + 250}
+		y -= 30
+		for i, header := range []string{labels.Date, labels.StartTime, labels.EndTime, labels.Hours, labels.Note} {
+			c.text(columns[i]+2, y, 10, true, header)
+		}
+		c.line(pdfMarginLeft, y-5, pdfPageWidth-pdfMarginRight, y-5, 1)
+
+		first := page * rowsPerPage
+		last := min(first+rowsPerPage, len(report.TableData))
+		for i := first; i < last; i++ {
+			row := report.TableData[i]
+			y -= pdfRowHeight
+			c.text(columns[0]+2, y, 10, false, formatPDFDate(row.Date))
+			c.text(columns[1]+2, y, 10, false, row.StartTime)
+			c.text(columns[2]+2, y, 10, false, row.EndTime)
+			c.text(columns[3]+2, y, 10, false, fmt.Sprintf("%.2f", totals.RowHours[i]))
+			c.text(columns[4]+2, y, 10, false, truncateRunes(row.Note, 45))
+			c.line(pdfMarginLeft, y-5, pdfPageWidth-pdfMarginRight, y-5, 0.3)
+		}
+
+		// Totals and signature on the last page
+		if page == pageCount-1 {
+			y -= 26
+			c.text(pdfMarginLeft+2, y, 11, true, labels.Total+":")
+			c.text(columns[3]+2, y, 11, true, fmt.Sprintf("%.2f", totals.TotalHours))
+
+			c.line(pdfPageWidth-pdfMarginRight-200, 90, pdfPageWidth-pdfMarginRight, 90, 0.5)
+			c.text(pdfPageWidth-pdfMarginRight-200, 76, 9, false, labels.Signature)
+		}
+
+		doc.addPage(c.buf.Bytes())
+	}
+
+	data := doc.bytes()
+
+	// Counted apart from the Excel report generated for the same request
+	m := metrics.GetMetrics()
+	m.RecordFileProcessed(metrics.StagePDF, metrics.StatusSuccess)
+	m.RecordProcessingDuration(metrics.StagePDF, time.Since(startTime))
+
+	return data, nil
+}
+
+func formatPDFDate(value string) string {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return value
+	}
+	return date.Format("02.01.2006")
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// pdfContent builds the content stream of a single page
+type pdfContent struct {
+	buf bytes.Buffer
+}
+
+func (c *pdfContent) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&c.buf, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, encodePDFText(s))
+}
+
+func (c *pdfContent) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&c.buf, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// encodePDFText converts s to the single-byte font encoding and escapes it for
+// use inside a PDF string literal.
+func encodePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r == '…':
+			b.WriteString("...")
+		default:
+			if code, ok := pdfGlyphCode(r); ok {
+				fmt.Fprintf(&b, "\\%03o", code)
+				continue
+			}
+			fallback := utils.RemoveDiacritics(string(r))
+			if len(fallback) == 1 && fallback[0] >= 32 && fallback[0] < 127 {
+				b.WriteString(fallback)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
+
+func pdfGlyphCode(r rune) (int, bool) {
+	for i, g := range pdfGlyphs {
+		if g.r == r {
+			return 128 + i, true
+		}
+	}
+	return 0, false
+}
+
+// pdfDocument assembles pages into a PDF file using the standard Helvetica
+// fonts, so no font files need to be embedded.
+type pdfDocument struct {
+	pages [][]byte
+}
+
+func newPDFDocument() *pdfDocument {
+	return &pdfDocument{}
+}
+
+func (d *pdfDocument) addPage(content []byte) {
+	d.pages = append(d.pages, content)
+}
+
+func (d *pdfDocument) bytes() []byte {
+	var objects []string
+
+	// Objects 1-5: catalog, page tree, regular and bold font, font encoding.
+	// Each page then adds a page object and a content stream object.
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 6+i*2))
+	}
+
+	var differences strings.Builder
+	differences.WriteString("128")
+	for _, g := range pdfGlyphs {
+		differences.WriteString(" /" + g.glyph)
+	}
+
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding 5 0 R >>",
+		fmt.Sprintf("<< /Type /Encoding /BaseEncoding /WinAnsiEncoding /Differences [%s] >>", differences.String()),
+	)
+
+	for i, content := range d.pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 7+i*2),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"timesheet-filler/internal/models"
+)
+
+func TestRenderTimesheet(t *testing.T) {
+	pdfService := NewPDFService(FixedReportMapping(DefaultReportMapping()))
+
+	var tableData []models.TableRow
+	for i := 0; i < MaxReportRows+1; i++ {
+		tableData = append(tableData, models.TableRow{
+			Date:      "2024-03-09",
+			StartTime: "18:00",
+			EndTime:   "20:00",
+			Note:      "Trénink (hřiště)",
+		})
+	}
+
+	data, err := pdfService.RenderTimesheet(PDFReport{
+		Name:      "Nováček Jan",
+		Period:    "03/2024",
+		TableData: tableData,
+		Labels:    PDFLabels{Title: "Výkaz práce", Total: "Celkem"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("Output is not framed as a PDF document")
+	}
+
+	// Entries beyond one template sheet continue on a second page
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Error("Expected a two page document")
+	}
+
+	// Parentheses are escaped and diacritics use the custom font encoding
+	code, _ := pdfGlyphCode('é')
+	if want := fmt.Sprintf("Tr\\%03onink \\(h", code); !bytes.Contains(data, []byte(want)) {
+		t.Errorf("Expected encoded note %q in content stream", want)
+	}
+
+	// Every xref entry must point at the start of its object
+	xref := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(data, -1)
+	for i, match := range xref {
+		offset, _ := strconv.Atoi(string(match[1]))
+		if prefix := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(data[offset:], []byte(prefix)) {
+			t.Errorf("xref entry %d points to %q, want %q", i+1, data[offset:offset+len(prefix)], prefix)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// errLocked is returned by acquireFileLock when another process holds the lock
+var errLocked = errors.New("lock is held by another process")
+
+// loadJSONFile decodes a file from the data directory into v. A missing data
+// directory or file leaves v untouched.
+func loadJSONFile(dataDir, name string, v interface{}) error {
+	if dataDir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dataDir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// saveJSONFile encodes v into a file in the data directory. The file is
+// replaced atomically so a crash never leaves a truncated file behind.
+func saveJSONFile(dataDir, name string, v interface{}) error {
+	if dataDir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(dataDir, name), data)
+}
+
+// jsonSnapshotStore persists the values of a store's map as a JSON list in a
+// file of the data directory, for stores that keep all their entries in
+// memory and write them out whole on every change
+type jsonSnapshotStore[K comparable, V any] struct {
+	dataDir string
+	name    string
+	// What the file holds, for log messages
+	label string
+	mutex sync.Mutex
+}
+
+func newJSONSnapshotStore[K comparable, V any](dataDir, name, label string) *jsonSnapshotStore[K, V] {
+	return &jsonSnapshotStore[K, V]{
+		dataDir: dataDir,
+		name:    name,
+		label:   label,
+	}
+}
+
+// load returns the values last saved, none without a data directory
+func (js *jsonSnapshotStore[K, V]) load() ([]V, error) {
+	var values []V
+	err := loadJSONFile(js.dataDir, js.name, &values)
+	return values, err
+}
+
+// save writes the values of entries, read under lock, to the data directory
+func (js *jsonSnapshotStore[K, V]) save(lock *sync.RWMutex, entries map[K]V) {
+	if js.dataDir == "" {
+		return
+	}
+
+	// Serialize writers so an older snapshot never replaces a newer one
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	lock.RLock()
+	values := make([]V, 0, len(entries))
+	for _, v := range entries {
+		values = append(values, v)
+	}
+	lock.RUnlock()
+
+	if err := saveJSONFile(js.dataDir, js.name, values); err != nil {
+		log.Printf("Error saving %s: %v", js.label, err)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// acquireFileLock takes an exclusive lock shared by every process using the
+// data directory, by creating the lock file. A lock older than staleAfter is
+// assumed to belong to a crashed process and is taken over. The returned
+// function releases the lock.
+func acquireFileLock(dataDir, name string, staleAfter time.Duration) (func(), error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dataDir, name)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.WriteString(time.Now().Format(time.RFC3339))
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		info, statErr := os.Stat(path)
+		if statErr != nil || time.Since(info.ModTime()) < staleAfter {
+			return nil, errLocked
+		}
+		os.Remove(path)
+	}
+
+	return nil, errLocked
+}
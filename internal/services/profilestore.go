@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+const profilesFileName = "profiles.json"
+
+// ProfileStore keeps member profiles, looked up by the member's name in the
+// attendance export ignoring case and diacritics. When a data directory is
+// configured, profiles are also written to disk.
+type ProfileStore struct {
+	profiles map[string]models.MemberProfile
+	mutex    sync.RWMutex
+	snapshot *jsonSnapshotStore[string, models.MemberProfile]
+}
+
+func NewProfileStore(dataDir string) *ProfileStore {
+	ps := &ProfileStore{
+		profiles: make(map[string]models.MemberProfile),
+		snapshot: newJSONSnapshotStore[string, models.MemberProfile](dataDir, profilesFileName, "member profiles"),
+	}
+
+	if err := ps.load(); err != nil {
+		log.Printf("Error loading member profiles: %v", err)
+	}
+
+	return ps
+}
+
+// Save creates or replaces the profile of a member
+func (ps *ProfileStore) Save(profile models.MemberProfile) (models.MemberProfile, error) {
+	profile.Member = strings.Join(strings.Fields(profile.Member), " ")
+	if profile.Member == "" {
+		return models.MemberProfile{}, fmt.Errorf("member name is required")
+	}
+	profile.FirstName = strings.TrimSpace(profile.FirstName)
+	profile.LastName = strings.TrimSpace(profile.LastName)
+	profile.Email = strings.TrimSpace(profile.Email)
+	profile.Team = strings.TrimSpace(profile.Team)
+	profile.UpdatedAt = time.Now()
+
+	ps.mutex.Lock()
+	ps.profiles[normalizeMemberName(profile.Member)] = profile
+	ps.mutex.Unlock()
+
+	ps.persist()
+
+	return profile, nil
+}
+
+// Get returns the profile of a member. A nil store knows no profiles.
+func (ps *ProfileStore) Get(member string) (models.MemberProfile, bool) {
+	if ps == nil {
+		return models.MemberProfile{}, false
+	}
+
+	ps.mutex.RLock()
+	profile, ok := ps.profiles[normalizeMemberName(member)]
+	ps.mutex.RUnlock()
+
+	return profile, ok
+}
+
+// Delete removes the profile of a member
+func (ps *ProfileStore) Delete(member string) bool {
+	ps.mutex.Lock()
+	key := normalizeMemberName(member)
+	_, ok := ps.profiles[key]
+	delete(ps.profiles, key)
+	ps.mutex.Unlock()
+
+	if ok {
+		ps.persist()
+	}
+	return ok
+}
+
+// List returns all profiles ordered by member name
+func (ps *ProfileStore) List() []models.MemberProfile {
+	ps.mutex.RLock()
+	profiles := make([]models.MemberProfile, 0, len(ps.profiles))
+	for _, profile := range ps.profiles {
+		profiles = append(profiles, profile)
+	}
+	ps.mutex.RUnlock()
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return normalizeMemberName(profiles[i].Member) < normalizeMemberName(profiles[j].Member)
+	})
+	return profiles
+}
+
+// SplitName returns the first and last name of a member as filled into
+// reports: from the member's profile when it has them, otherwise guessed
+// from the name in the export
+func (ps *ProfileStore) SplitName(member string) (firstname, lastname string) {
+	if profile, ok := ps.Get(member); ok && (profile.FirstName != "" || profile.LastName != "") {
+		return profile.FirstName, profile.LastName
+	}
+	return utils.SplitName(member)
+}
+
+// load reads previously persisted profiles from the data directory
+func (ps *ProfileStore) load() error {
+	profiles, err := ps.snapshot.load()
+	if err != nil {
+		return err
+	}
+
+	ps.mutex.Lock()
+	for _, profile := range profiles {
+		ps.profiles[normalizeMemberName(profile.Member)] = profile
+	}
+	ps.mutex.Unlock()
+
+	return nil
+}
+
+// persist writes all profiles to the data directory
+func (ps *ProfileStore) persist() {
+	ps.snapshot.save(&ps.mutex, ps.profiles)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+
+	"timesheet-filler/internal/models"
+)
+
+func TestProfileStore(t *testing.T) {
+	dataDir := t.TempDir()
+	store := NewProfileStore(dataDir)
+
+	if _, err := store.Save(models.MemberProfile{Member: "  "}); err == nil {
+		t.Error("Expected a profile without a member name to be rejected")
+	}
+
+	profile, err := store.Save(models.MemberProfile{
+		Member:     " Nováková  Svobodová Jana ",
+		FirstName:  "Jana",
+		LastName:   "Nováková Svobodová",
+		Email:      "jana@example.com ",
+		Language:   "cs",
+		Team:       "U19",
+		Recipients: []string{"coach@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if profile.Member != "Nováková Svobodová Jana" || profile.Email != "jana@example.com" || profile.UpdatedAt.IsZero() {
+		t.Errorf("Expected the profile to be normalized, got %+v", profile)
+	}
+
+	// Members are looked up ignoring case and diacritics
+	if got, ok := store.Get("novakova svobodova jana"); !ok || got.Team != "U19" {
+		t.Errorf("Expected the profile to be found, got %+v", got)
+	}
+
+	tests := []struct {
+		member    string
+		firstname string
+		lastname  string
+	}{
+		{"Nováková Svobodová Jana", "Jana", "Nováková Svobodová"},
+		{"Novák Jan", "Jan", "Novák"},
+	}
+	for _, tt := range tests {
+		firstname, lastname := store.SplitName(tt.member)
+		if firstname != tt.firstname || lastname != tt.lastname {
+			t.Errorf("Expected %q to be split into %q and %q, got %q and %q", tt.member, tt.firstname, tt.lastname, firstname, lastname)
+		}
+	}
+
+	var noStore *ProfileStore
+	if firstname, lastname := noStore.SplitName("Novák Jan"); firstname != "Jan" || lastname != "Novák" {
+		t.Errorf("Expected a nil store to guess the name, got %q and %q", firstname, lastname)
+	}
+
+	// Profiles are reloaded from the data directory
+	store.Save(models.MemberProfile{Member: "Dvořák Petr", Email: "petr@example.com"})
+	reloaded := NewProfileStore(dataDir)
+	profiles := reloaded.List()
+	if len(profiles) != 2 || profiles[0].Member != "Dvořák Petr" || profiles[1].Recipients[0] != "coach@example.com" {
+		t.Errorf("Expected 2 persisted profiles ordered by member, got %+v", profiles)
+	}
+
+	if !reloaded.Delete("DVORAK PETR") || reloaded.Delete("Dvořák Petr") {
+		t.Error("Expected the profile to be deleted once")
+	}
+	if len(NewProfileStore(dataDir).List()) != 1 {
+		t.Error("Expected the deletion to be persisted")
+	}
+}
+
+func TestProcessExcelFileMemberProfile(t *testing.T) {
+	profiles := NewProfileStore("")
+	profiles.Save(models.MemberProfile{Member: "Jana Nováková Svobodová", FirstName: "Jana", LastName: "Nováková Svobodová"})
+	excelService := NewExcelService(bundledTemplatePath, "docházka", WithMemberProfiles(profiles))
+	mapping := excelService.GetReportMapping()
+
+	f, err := excelService.ProcessExcelFile("Jana Nováková Svobodová", []models.TableRow{{Date: "2024-03-04", StartTime: "18:00", EndTime: "20:00"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer f.Close()
+
+	firstname, _ := f.GetCellValue(mapping.TargetSheet, mapping.FirstNameCell)
+	lastname, _ := f.GetCellValue(mapping.TargetSheet, mapping.LastNameCell)
+	if firstname != "Jana" || lastname != "Nováková Svobodová" {
+		t.Errorf("Expected the names of the profile, got %q and %q", firstname, lastname)
+	}
+}
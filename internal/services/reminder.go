@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"timesheet-filler/internal/i18n"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+const (
+	remindersFileName = "reminders.json"
+	remindersLockName = "reminders.lock"
+	// A run holding the lock longer than this is assumed to have crashed
+	reminderLockStaleAfter = 30 * time.Minute
+)
+
+// ReminderOptions configures the reminder scheduler
+type ReminderOptions struct {
+	Schedule     *CronSchedule
+	Location     *time.Location
+	MemberEmails map[string]string
+	PublicURL    string
+	Language     string
+	DataDir      string
+	// Member profiles, whose email address and language take precedence
+	Profiles *ProfileStore
+	// Issues the personal links of members, linked instead of the upload
+	// page when set
+	Links *PersonalLinkService
+}
+
+// Reminder is a single reminder email for a member and period
+type Reminder struct {
+	Name  string
+	Email string
+	Year  int
+	Month int
+	Link  string
+}
+
+// ReminderService emails members who attended events in the past month but
+// have not submitted their timesheet yet. Sent reminders are recorded, and
+// with a data directory a lock file keeps replicas sharing it from sending
+// the same reminder twice.
+type ReminderService struct {
+	dashboardService *DashboardService
+	submissionStore  *SubmissionStore
+	emailService     *EmailService
+	translator       *i18n.Translator
+	options          ReminderOptions
+	contacts         *MemberContacts
+	sent             map[string]time.Time
+	mutex            sync.Mutex
+}
+
+func NewReminderService(
+	dashboardService *DashboardService,
+	submissionStore *SubmissionStore,
+	emailService *EmailService,
+	translator *i18n.Translator,
+	options ReminderOptions,
+) *ReminderService {
+	if options.Location == nil {
+		options.Location = time.Local
+	}
+
+	return &ReminderService{
+		dashboardService: dashboardService,
+		submissionStore:  submissionStore,
+		emailService:     emailService,
+		translator:       translator,
+		options:          options,
+		contacts:         NewMemberContacts(options.Profiles, options.MemberEmails, submissionStore),
+		sent:             make(map[string]time.Time),
+	}
+}
+
+// ParseMemberEmails parses "Lastname Firstname=email" pairs
+func ParseMemberEmails(pairs []string) map[string]string {
+	emails := make(map[string]string)
+	for _, pair := range pairs {
+		name, email, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(email) == "" {
+			continue
+		}
+		emails[strings.TrimSpace(name)] = strings.TrimSpace(email)
+	}
+	return emails
+}
+
+// Start runs reminders on the schedule until the context is cancelled
+func (rs *ReminderService) Start(ctx context.Context) {
+	for {
+		next := rs.options.Schedule.Next(time.Now().In(rs.options.Location))
+		if next.IsZero() {
+			log.Printf("Reminder schedule never matches, scheduler stopped")
+			return
+		}
+		log.Printf("Next timesheet reminder run at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		sent, err := rs.Run(next)
+		if err != nil {
+			log.Printf("Error sending timesheet reminders: %v", err)
+			continue
+		}
+		log.Printf("Sent %d timesheet reminders", sent)
+	}
+}
+
+// Run sends the reminders for the month before now and returns how many were
+// sent. Nothing is sent while another replica is running.
+func (rs *ReminderService) Run(now time.Time) (int, error) {
+	if !rs.emailService.IsConfigured() {
+		return 0, errors.New("email service is not configured")
+	}
+
+	if rs.options.DataDir != "" {
+		release, err := acquireFileLock(rs.options.DataDir, remindersLockName, reminderLockStaleAfter)
+		if errors.Is(err, errLocked) {
+			log.Printf("Reminders are being sent by another replica, skipping")
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		defer release()
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	// Another replica may have sent reminders since the last run
+	if err := loadJSONFile(rs.options.DataDir, remindersFileName, &rs.sent); err != nil {
+		return 0, err
+	}
+
+	reminders, err := rs.Pending(now)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, reminder := range reminders {
+		key := reminderKey(reminder.Name, reminder.Year, reminder.Month)
+		if _, ok := rs.sent[key]; ok {
+			continue
+		}
+
+		lang := rs.contacts.Language(reminder.Name, rs.options.Language)
+		subject := fmt.Sprintf(rs.translator.Translate("email_reminder_subject", lang), reminder.Month, reminder.Year)
+		// The body is sent as HTML and the name comes from the export
+		body := fmt.Sprintf(rs.translator.Translate("email_reminder_body", lang),
+			html.EscapeString(reminder.Name), reminder.Month, reminder.Year, html.EscapeString(reminder.Link))
+		if err := rs.emailService.SendEmail(subject, body, []string{reminder.Email}, nil); err != nil {
+			log.Printf("Error sending reminder to %s: %v", reminder.Name, err)
+			continue
+		}
+
+		rs.sent[key] = time.Now()
+		sent++
+	}
+
+	if err := saveJSONFile(rs.options.DataDir, remindersFileName, rs.sent); err != nil {
+		return sent, err
+	}
+
+	return sent, nil
+}
+
+// Pending returns the reminders due for the month before now: members with
+// attended events in the latest export whose report is neither waiting for
+// approval nor approved. Members without a known email address are skipped.
+func (rs *ReminderService) Pending(now time.Time) ([]Reminder, error) {
+	now = now.In(rs.options.Location)
+	previous := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, rs.options.Location).AddDate(0, -1, 0)
+	year, month := previous.Year(), int(previous.Month())
+
+	rows, err := rs.dashboardService.BuildLatest(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	var reminders []Reminder
+	for _, row := range rows {
+		if row.Events == 0 || row.SubmissionStatus == models.SubmissionPending || row.SubmissionStatus == models.SubmissionApproved {
+			continue
+		}
+
+		email, ok := rs.contacts.Email(row.Name)
+		if !ok {
+			log.Printf("No email address known for %s, skipping reminder", row.Name)
+			continue
+		}
+
+		reminders = append(reminders, Reminder{
+			Name:  row.Name,
+			Email: email,
+			Year:  year,
+			Month: month,
+			Link:  rs.deepLink(row.Name, year, month, now),
+		})
+	}
+
+	return reminders, nil
+}
+
+// deepLink opens the member's personal page for the month, or the upload
+// page with the member and month preselected
+func (rs *ReminderService) deepLink(name string, year, month int, now time.Time) string {
+	if rs.options.Links != nil {
+		return rs.options.Links.Issue(name, year, month, now).URL
+	}
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("month", strconv.Itoa(month))
+	return strings.TrimSuffix(rs.options.PublicURL, "/") + "/?" + query.Encode()
+}
+
+func reminderKey(name string, year, month int) string {
+	return fmt.Sprintf("%s|%d-%02d", name, year, month)
+}
+
+func normalizeMemberName(name string) string {
+	return strings.ToLower(utils.RemoveDiacritics(strings.Join(strings.Fields(name), " ")))
+}
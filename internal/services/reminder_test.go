@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/testutil"
+)
+
+func TestReminderPending(t *testing.T) {
+	excelService := NewExcelService("", "docházka realizačního týmu")
+	versionStore := NewVersionStore("")
+	submissionStore := NewSubmissionStore("")
+	profileStore := NewProfileStore("")
+	dashboardService := NewDashboardService(excelService, versionStore, submissionStore, nil, t.TempDir())
+	reminderService := NewReminderService(dashboardService, submissionStore, nil, nil, ReminderOptions{
+		MemberEmails: ParseMemberEmails([]string{"test user=test@example.com", "invalid"}),
+		PublicURL:    "https://timesheet.example.com/",
+		Profiles:     profileStore,
+	})
+
+	if _, err := reminderService.Pending(time.Date(2023, 2, 2, 9, 0, 0, 0, time.Local)); err == nil {
+		t.Fatal("Expected an error without an uploaded export")
+	}
+
+	dashboardService.SaveExport(testutil.CreateTestExcelFile(t), ParseContext{})
+
+	reminders, err := reminderService.Pending(time.Date(2023, 2, 2, 9, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Another User has events too, but no known email address
+	if len(reminders) != 1 {
+		t.Fatalf("Expected 1 reminder, got %d: %+v", len(reminders), reminders)
+	}
+	reminder := reminders[0]
+	if reminder.Name != "Test User" || reminder.Email != "test@example.com" || reminder.Year != 2023 || reminder.Month != 1 {
+		t.Errorf("Unexpected reminder %+v", reminder)
+	}
+	if want := "https://timesheet.example.com/?month=1&name=Test+User"; reminder.Link != want {
+		t.Errorf("Expected link %q, got %q", want, reminder.Link)
+	}
+
+	// With personal links, the reminder links to the member's own page
+	reminderService.options.Links = NewPersonalLinkService([]byte("secret"), time.Hour, "https://timesheet.example.com")
+	reminders, _ = reminderService.Pending(time.Date(2023, 2, 2, 9, 0, 0, 0, time.Local))
+	if len(reminders) != 1 || !strings.HasPrefix(reminders[0].Link, "https://timesheet.example.com/me?token=") {
+		t.Errorf("Expected a personal link, got %+v", reminders)
+	}
+	reminderService.options.Links = nil
+
+	// The address of a member's profile is used too
+	profileStore.Save(models.MemberProfile{Member: "Another User", Email: "profile@example.com"})
+	reminders, _ = reminderService.Pending(time.Date(2023, 2, 2, 9, 0, 0, 0, time.Local))
+	if len(reminders) != 2 || (reminders[0].Email != "profile@example.com" && reminders[1].Email != "profile@example.com") {
+		t.Errorf("Expected a reminder to the profile address, got %+v", reminders)
+	}
+
+	// A pending submission stops the reminder, and its email is remembered
+	submissionStore.Submit(versionStore.Add("Another User", 2023, 1, nil, "another.xlsx"), "another@example.com")
+	submissionStore.Submit(versionStore.Add("Test User", 2023, 1, nil, "test.xlsx"), "")
+	reminders, _ = reminderService.Pending(time.Date(2023, 2, 2, 9, 0, 0, 0, time.Local))
+	if len(reminders) != 0 {
+		t.Errorf("Expected no reminders after submitting, got %+v", reminders)
+	}
+	if email, ok := submissionStore.MemberEmail("Another User"); !ok || email != "another@example.com" {
+		t.Errorf("Expected the submission email to be remembered, got %q", email)
+	}
+
+	// No attended events in February
+	reminders, _ = reminderService.Pending(time.Date(2023, 3, 1, 9, 0, 0, 0, time.Local))
+	if len(reminders) != 0 {
+		t.Errorf("Expected no reminders for February, got %+v", reminders)
+	}
+}
+
+func TestAcquireFileLock(t *testing.T) {
+	dir := t.TempDir()
+
+	release, err := acquireFileLock(dir, "test.lock", time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := acquireFileLock(dir, "test.lock", time.Hour); !errors.Is(err, errLocked) {
+		t.Errorf("Expected the lock to be held, got %v", err)
+	}
+
+	// A stale lock is taken over
+	releaseStale, err := acquireFileLock(dir, "test.lock", 0)
+	if err != nil {
+		t.Fatalf("Expected a stale lock to be taken over, got %v", err)
+	}
+	releaseStale()
+
+	release()
+	release, err = acquireFileLock(dir, "test.lock", time.Hour)
+	if err != nil {
+		t.Fatalf("Expected the released lock to be free, got %v", err)
+	}
+	release()
+}
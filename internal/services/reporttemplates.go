@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+const (
+	reportTemplatesFileName = "report_templates.json"
+	// Directory in the data directory holding the uploaded workbooks
+	reportTemplatesDir = "report_templates"
+)
+
+// Report template issue codes, rendered with the "validation_<code>"
+// translation keys like the issues of the edit page
+const (
+	IssueTemplateUnreadable    = "template_unreadable"
+	IssueTemplateSheetMissing  = "template_sheet_missing"
+	IssueTemplateInvalidCell   = "template_invalid_cell"
+	IssueTemplateInvalidColumn = "template_invalid_column"
+	IssueTemplateInvalidRows   = "template_invalid_rows"
+	IssueTemplateCellInRows    = "template_cell_in_rows"
+	IssueTemplateStyleMissing  = "template_style_missing"
+)
+
+// ReportTemplateStore keeps the report templates uploaded by admins. When a
+// data directory is configured, they are also written to disk.
+type ReportTemplateStore struct {
+	templates map[string]models.ReportTemplate
+	files     map[string][]byte
+	mutex     sync.RWMutex
+	snapshot  *jsonSnapshotStore[string, models.ReportTemplate]
+	dataDir   string
+}
+
+func NewReportTemplateStore(dataDir string) *ReportTemplateStore {
+	rs := &ReportTemplateStore{
+		templates: make(map[string]models.ReportTemplate),
+		files:     make(map[string][]byte),
+		dataDir:   dataDir,
+		snapshot:  newJSONSnapshotStore[string, models.ReportTemplate](dataDir, reportTemplatesFileName, "report templates"),
+	}
+
+	if err := rs.load(); err != nil {
+		log.Printf("Error loading report templates: %v", err)
+	}
+
+	return rs
+}
+
+// Add stores an uploaded template, inactive until it is activated
+func (rs *ReportTemplateStore) Add(tmpl models.ReportTemplate, data []byte) (models.ReportTemplate, error) {
+	tmpl.ID = utils.GenerateToken()
+	tmpl.Active = false
+	tmpl.UploadedAt = time.Now()
+
+	if rs.dataDir != "" {
+		if err := writeFileAtomic(rs.templatePath(tmpl.ID), data); err != nil {
+			return models.ReportTemplate{}, fmt.Errorf("failed to save report template: %w", err)
+		}
+	}
+
+	rs.mutex.Lock()
+	rs.templates[tmpl.ID] = tmpl
+	rs.files[tmpl.ID] = data
+	rs.mutex.Unlock()
+
+	rs.persist()
+
+	return tmpl, nil
+}
+
+// Get returns a template and its workbook
+func (rs *ReportTemplateStore) Get(id string) (models.ReportTemplate, []byte, bool) {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	tmpl, ok := rs.templates[id]
+	return tmpl, rs.files[id], ok
+}
+
+// List returns all templates, newest first
+func (rs *ReportTemplateStore) List() []models.ReportTemplate {
+	rs.mutex.RLock()
+	templates := make([]models.ReportTemplate, 0, len(rs.templates))
+	for _, tmpl := range rs.templates {
+		templates = append(templates, tmpl)
+	}
+	rs.mutex.RUnlock()
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].UploadedAt.After(templates[j].UploadedAt)
+	})
+	return templates
+}
+
+// Activate makes a template be used for the report periods from validFrom
+// to validTo. Either of them may be zero for an open range.
+func (rs *ReportTemplateStore) Activate(id string, validFrom, validTo time.Time) (models.ReportTemplate, error) {
+	if !validFrom.IsZero() && !validTo.IsZero() && validTo.Before(validFrom) {
+		return models.ReportTemplate{}, fmt.Errorf("validity ends before it starts")
+	}
+
+	rs.mutex.Lock()
+	tmpl, ok := rs.templates[id]
+	if !ok {
+		rs.mutex.Unlock()
+		return models.ReportTemplate{}, fmt.Errorf("report template %q not found", id)
+	}
+	tmpl.Active = true
+	tmpl.ValidFrom = validFrom
+	tmpl.ValidTo = validTo
+	rs.templates[id] = tmpl
+	rs.mutex.Unlock()
+
+	rs.persist()
+
+	return tmpl, nil
+}
+
+// Deactivate stops a template from being used for new reports
+func (rs *ReportTemplateStore) Deactivate(id string) error {
+	rs.mutex.Lock()
+	tmpl, ok := rs.templates[id]
+	if !ok {
+		rs.mutex.Unlock()
+		return fmt.Errorf("report template %q not found", id)
+	}
+	tmpl.Active = false
+	rs.templates[id] = tmpl
+	rs.mutex.Unlock()
+
+	rs.persist()
+
+	return nil
+}
+
+// For returns the active template covering the report period starting at
+// period. Of overlapping templates the one whose validity starts latest
+// wins, then the newest.
+func (rs *ReportTemplateStore) For(period time.Time) (models.ReportTemplate, []byte, bool) {
+	var best models.ReportTemplate
+	found := false
+
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	for _, tmpl := range rs.templates {
+		if !tmpl.Covers(period) {
+			continue
+		}
+		if !found || tmpl.ValidFrom.After(best.ValidFrom) ||
+			(tmpl.ValidFrom.Equal(best.ValidFrom) && tmpl.UploadedAt.After(best.UploadedAt)) {
+			best = tmpl
+			found = true
+		}
+	}
+	if !found {
+		return models.ReportTemplate{}, nil, false
+	}
+	return best, rs.files[best.ID], true
+}
+
+// Mappings returns the mappings of the active templates
+func (rs *ReportTemplateStore) Mappings() []models.ReportMapping {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	var mappings []models.ReportMapping
+	for _, tmpl := range rs.templates {
+		if tmpl.Active {
+			mappings = append(mappings, tmpl.Mapping)
+		}
+	}
+	return mappings
+}
+
+func (rs *ReportTemplateStore) templatePath(id string) string {
+	return filepath.Join(rs.dataDir, reportTemplatesDir, id+".xlsx")
+}
+
+// load reads previously persisted templates and their workbooks from the
+// data directory
+func (rs *ReportTemplateStore) load() error {
+	templates, err := rs.snapshot.load()
+	if err != nil {
+		return err
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	for _, tmpl := range templates {
+		data, err := os.ReadFile(rs.templatePath(tmpl.ID))
+		if err != nil {
+			log.Printf("Error loading report template %q: %v", tmpl.Name, err)
+			continue
+		}
+		rs.templates[tmpl.ID] = tmpl
+		rs.files[tmpl.ID] = data
+	}
+
+	return nil
+}
+
+// persist writes the list of templates to the data directory
+func (rs *ReportTemplateStore) persist() {
+	rs.snapshot.save(&rs.mutex, rs.templates)
+}
+
+// ValidateReportTemplate checks that an uploaded template fits its mapping:
+// the target sheet exists, the mapped cells and columns are valid and the
+// fixed cells do not fall into the entry rows
+func ValidateReportTemplate(data []byte, mapping models.ReportMapping) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+	issue := func(code string, args ...interface{}) {
+		issues = append(issues, newIssue(tableWideIssueRowIdx, code, SeverityError, args...))
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		issue(IssueTemplateUnreadable)
+		return issues
+	}
+	defer f.Close()
+
+	if idx, err := f.GetSheetIndex(mapping.TargetSheet); err != nil || idx == -1 {
+		issue(IssueTemplateSheetMissing, mapping.TargetSheet)
+	}
+
+	issues = append(issues, ValidateReportMapping(mapping)...)
+
+	if _, err := f.GetStyle(mapping.TimeStyleID); err != nil {
+		issue(IssueTemplateStyleMissing, mapping.TimeStyleID)
+	}
+
+	return issues
+}
+
+// ValidateReportMapping checks the mapping of a report template on its own:
+// the entry rows, cells and columns are valid and the fixed cells do not fall
+// into the entry rows
+func ValidateReportMapping(mapping models.ReportMapping) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+	issue := func(code string, args ...interface{}) {
+		issues = append(issues, newIssue(tableWideIssueRowIdx, code, SeverityError, args...))
+	}
+
+	lastRow := mapping.StartRow + mapping.MaxRows - 1
+	rowsValid := mapping.StartRow >= 1 && mapping.MaxRows >= 1 && lastRow <= excelize.TotalRows
+	if !rowsValid {
+		issue(IssueTemplateInvalidRows, mapping.StartRow, mapping.MaxRows)
+	}
+
+	entryColumns := make(map[int]bool)
+	for _, column := range []struct {
+		name     string
+		required bool
+	}{
+		{mapping.DateColumn, true},
+		{mapping.StartTimeColumn, true},
+		{mapping.EndTimeColumn, true},
+		{mapping.NoteColumn, true},
+		{mapping.HoursColumn, false},
+		{mapping.HolidayColumn, false},
+	} {
+		if column.name == "" && !column.required {
+			continue
+		}
+		number, err := excelize.ColumnNameToNumber(column.name)
+		if err != nil {
+			issue(IssueTemplateInvalidColumn, column.name)
+			continue
+		}
+		entryColumns[number] = true
+	}
+
+	for _, cell := range []struct {
+		name     string
+		required bool
+	}{
+		{mapping.FirstNameCell, true},
+		{mapping.LastNameCell, true},
+		{mapping.TotalHoursCell, false},
+		{mapping.ApprovedByCell, false},
+		{mapping.ApprovedAtCell, false},
+		{mapping.ApprovedByLabelCell, false},
+		{mapping.ApprovedAtLabelCell, false},
+	} {
+		if cell.name == "" && !cell.required {
+			continue
+		}
+		col, row, err := excelize.CellNameToCoordinates(cell.name)
+		if err != nil {
+			issue(IssueTemplateInvalidCell, cell.name)
+			continue
+		}
+		// Entries would overwrite the cell
+		if rowsValid && entryColumns[col] && row >= mapping.StartRow && row <= lastRow {
+			issue(IssueTemplateCellInRows, cell.name)
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"timesheet-filler/internal/models"
+)
+
+const bundledTemplatePath = "../../gorily_timesheet_template_2024.xlsx"
+
+// createReportTemplate returns a minimal report template and its mapping
+func createReportTemplate(t *testing.T) ([]byte, models.ReportMapping) {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	f.SetSheetName("Sheet1", "timesheet")
+	f.SetCellValue("timesheet", "A1", "Name")
+	timeStyle, err := f.NewStyle(&excelize.Style{NumFmt: 20})
+	if err != nil {
+		t.Fatalf("failed to create style: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	return buf.Bytes(), models.ReportMapping{
+		TargetSheet:     "timesheet",
+		FirstNameCell:   "B1",
+		LastNameCell:    "C1",
+		StartRow:        3,
+		MaxRows:         20,
+		DateColumn:      "A",
+		StartTimeColumn: "B",
+		EndTimeColumn:   "C",
+		NoteColumn:      "D",
+		TotalHoursCell:  "E1",
+		TimeStyleID:     timeStyle,
+	}
+}
+
+func TestValidateReportTemplate(t *testing.T) {
+	bundled, err := os.ReadFile(bundledTemplatePath)
+	if err != nil {
+		t.Fatalf("failed to read bundled template: %v", err)
+	}
+	if issues := ValidateReportTemplate(bundled, DefaultReportMapping()); len(issues) != 0 {
+		t.Errorf("Expected the bundled template to be valid, got %+v", issues)
+	}
+
+	data, mapping := createReportTemplate(t)
+	if issues := ValidateReportTemplate(data, mapping); len(issues) != 0 {
+		t.Errorf("Expected the template to be valid, got %+v", issues)
+	}
+
+	mapping.TargetSheet = "výkaz práce"
+	mapping.FirstNameCell = "B"
+	mapping.TotalHoursCell = "D10"
+	mapping.NoteColumn = "4"
+	mapping.TimeStyleID = 99
+	codes := make(map[string]bool)
+	for _, issue := range ValidateReportTemplate(data, mapping) {
+		codes[issue.Code] = true
+	}
+	for _, code := range []string{IssueTemplateSheetMissing, IssueTemplateInvalidCell, IssueTemplateInvalidColumn, IssueTemplateStyleMissing} {
+		if !codes[code] {
+			t.Errorf("Expected issue %q, got %v", code, codes)
+		}
+	}
+
+	mapping.NoteColumn = "D"
+	mapping.StartRow = 0
+	codes = make(map[string]bool)
+	for _, issue := range ValidateReportTemplate(data, mapping) {
+		codes[issue.Code] = true
+	}
+	if !codes[IssueTemplateInvalidRows] {
+		t.Errorf("Expected issue %q, got %v", IssueTemplateInvalidRows, codes)
+	}
+
+	mapping.StartRow = 3
+	codes = make(map[string]bool)
+	for _, issue := range ValidateReportTemplate(data, mapping) {
+		codes[issue.Code] = true
+	}
+	if !codes[IssueTemplateCellInRows] {
+		t.Errorf("Expected the total in the entry rows to be reported, got %v", codes)
+	}
+
+	if issues := ValidateReportTemplate([]byte("not a workbook"), mapping); len(issues) != 1 || issues[0].Code != IssueTemplateUnreadable {
+		t.Errorf("Expected an unreadable template, got %+v", issues)
+	}
+}
+
+func TestReportTemplateStore(t *testing.T) {
+	dataDir := t.TempDir()
+	store := NewReportTemplateStore(dataDir)
+	data, mapping := createReportTemplate(t)
+
+	older, err := store.Add(models.ReportTemplate{Name: "2025", Filename: "2025.xlsx", Mapping: mapping}, data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	newer, err := store.Add(models.ReportTemplate{Name: "summer", Filename: "summer.xlsx", Mapping: mapping}, data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if _, _, ok := store.For(march); ok {
+		t.Error("Expected inactive templates not to be used")
+	}
+
+	if _, err := store.Activate(older.ID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := store.Activate(newer.ID, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 8, 31, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := store.Activate(newer.ID, time.Date(2025, 8, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("Expected a range ending before it starts to be rejected")
+	}
+
+	tests := []struct {
+		period time.Time
+		want   string
+	}{
+		{time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), ""},
+		{march, "2025"},
+		{time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), "summer"},
+		{time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), "2025"},
+	}
+	for _, tt := range tests {
+		tmpl, tmplData, ok := store.For(tt.period)
+		if tmpl.Name != tt.want || ok != (tt.want != "") || (ok && !bytes.Equal(tmplData, data)) {
+			t.Errorf("Expected template %q for %s, got %q", tt.want, tt.period.Format("2006-01"), tmpl.Name)
+		}
+	}
+
+	// Templates and their workbooks are reloaded from the data directory
+	if err := store.Deactivate(older.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	reloaded := NewReportTemplateStore(dataDir)
+	if len(reloaded.List()) != 2 {
+		t.Fatalf("Expected 2 persisted templates, got %d", len(reloaded.List()))
+	}
+	if _, _, ok := reloaded.For(march); ok {
+		t.Error("Expected the deactivated template to stay inactive")
+	}
+	if tmpl, tmplData, ok := reloaded.For(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)); !ok || tmpl.ID != newer.ID || !bytes.Equal(tmplData, data) {
+		t.Errorf("Expected the persisted summer template, got %+v", tmpl)
+	}
+}
+
+func TestProcessExcelFileReportTemplates(t *testing.T) {
+	store := NewReportTemplateStore("")
+	data, mapping := createReportTemplate(t)
+	tmpl, err := store.Add(models.ReportTemplate{Name: "2025", Filename: "2025.xlsx", Mapping: mapping}, data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := store.Activate(tmpl.ID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	excelService := NewExcelService(bundledTemplatePath, "docházka", WithReportTemplates(store))
+
+	// Reports of periods the uploaded template covers use it
+	rows := []models.TableRow{{Date: "2025-03-04", StartTime: "18:00", EndTime: "20:00", Note: "Practice"}}
+	f, err := excelService.ProcessExcelFile("Novák Jan", rows)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lastname, _ := f.GetCellValue(mapping.TargetSheet, mapping.LastNameCell)
+	note, _ := f.GetCellValue(mapping.TargetSheet, "D3")
+	if lastname != "Novák" || note != "Practice" {
+		t.Errorf("Expected the report filled into the uploaded template, got %q and %q", lastname, note)
+	}
+	var buf bytes.Buffer
+	f.Write(&buf)
+	f.Close()
+
+	// and are read back with its mapping
+	if !excelService.IsGeneratedReport(buf.Bytes()) {
+		t.Error("Expected the report to be recognised as generated")
+	}
+	name, extracted, err := excelService.ExtractReportData(buf.Bytes())
+	if err != nil || name != "Novák Jan" || len(extracted) != 1 || extracted[0].Date != "2025-03-04" {
+		t.Errorf("Expected the entry to be read back, got %q %+v (%v)", name, extracted, err)
+	}
+
+	// Earlier periods keep the configured template
+	f, err = excelService.ProcessExcelFile("Novák Jan", []models.TableRow{{Date: "2024-03-04", StartTime: "18:00", EndTime: "20:00"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer f.Close()
+	if idx, _ := f.GetSheetIndex(DefaultReportMapping().TargetSheet); idx == -1 {
+		t.Error("Expected the configured template for 2024")
+	}
+
+	// Validation warns about rows beyond the sheet of the period's template
+	validationService := NewValidationService(0, excelService.ReportMappingFor, false, EventSplitOptions{}, nil)
+	var many []models.TableRow
+	for day := 1; day <= mapping.MaxRows+1; day++ {
+		many = append(many, models.TableRow{Date: fmt.Sprintf("2025-03-%02d", day%28+1), StartTime: "08:00", EndTime: "09:00"})
+	}
+	tooMany := false
+	for _, issue := range validationService.Validate(many, 3) {
+		tooMany = tooMany || issue.Code == IssueTooManyRows
+	}
+	if !tooMany {
+		t.Errorf("Expected a warning for more than the %d rows of the uploaded template", mapping.MaxRows)
+	}
+}
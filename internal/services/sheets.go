@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+// confidentSheetScore is how many of the expected columns the header row of
+// a sheet must have in place for the sheet to be picked without asking
+const confidentSheetScore = 4
+
+// previewRows is how many rows of each candidate sheet the sheet selection
+// page shows, header included
+const previewRows = 4
+
+// AmbiguousSheetError is returned when no sheet of a workbook clearly looks
+// like an attendance export. Candidates hold every sheet, best first.
+type AmbiguousSheetError struct {
+	Candidates []models.SheetCandidate
+}
+
+func (e AmbiguousSheetError) Error() string {
+	return "no sheet clearly matches the columns of the attendance export"
+}
+
+// sourceField is a column of the attendance export, read by position
+type sourceField struct {
+	field  string
+	index  int
+	header string // header of the column in exports of the attendance system
+}
+
+func (es *ExcelService) sourceFields() []sourceField {
+	return []sourceField{
+		{"member", es.idxClen, "Člen"},
+		{"attended", es.idxAttended, "Účast potvrzena"},
+		{"event_type", es.idxTypUdalosti, "Typ události"},
+		{"event_name", es.idxNazevUdalosti, "Název události"},
+		{"start", es.idxDatum1, "Od"},
+		{"end", es.idxDatum2, "Do"},
+	}
+}
+
+// IsSheetPattern reports whether a source sheet selection is a glob pattern
+// such as "tým *" rather than the name of a single sheet
+func IsSheetPattern(selection string) bool {
+	return strings.ContainsAny(selection, "*?[")
+}
+
+// MatchSheets returns the sheets of a workbook, in workbook order, that one
+// of the selections names or matches as a pattern. Selected names missing
+// from the workbook are returned separately.
+func MatchSheets(sheets, selections []string) (matched, missing []string) {
+	selected := make(map[string]bool)
+	for _, selection := range selections {
+		if IsSheetPattern(selection) {
+			for _, sheet := range sheets {
+				if ok, _ := path.Match(selection, sheet); ok {
+					selected[sheet] = true
+				}
+			}
+			continue
+		}
+
+		found := false
+		for _, sheet := range sheets {
+			if sheet == selection {
+				selected[sheet] = true
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, selection)
+		}
+	}
+
+	for _, sheet := range sheets {
+		if selected[sheet] {
+			matched = append(matched, sheet)
+		}
+	}
+	return matched, missing
+}
+
+// ParseContext holds the choices of a session about how its attendance
+// exports are read. It is passed with every call instead of being set on
+// the ExcelService, which is shared by all sessions.
+type ParseContext struct {
+	// Sheets to read rows from, names or patterns. The configured source
+	// sheet is read when there are none.
+	Sheets []string
+}
+
+// sheetsFor returns the sheet selections of a session, falling back to the
+// configured source sheet
+func (es *ExcelService) sheetsFor(pc ParseContext) []string {
+	var sheets []string
+	for _, selection := range pc.Sheets {
+		if selection = strings.TrimSpace(selection); selection != "" {
+			sheets = append(sheets, selection)
+		}
+	}
+	if len(sheets) == 0 && es.sourceSheet != "" {
+		sheets = []string{es.sourceSheet}
+	}
+	return sheets
+}
+
+// ScoreSheets rates every sheet of a workbook by how many of the expected
+// columns its header row has at their position, best first and in workbook
+// order among equals. Each candidate comes with its first rows as a preview.
+func (es *ExcelService) ScoreSheets(fileData []byte) ([]models.SheetCandidate, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(fileData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer f.Close()
+
+	fields := es.sourceFields()
+	width := 0
+	for _, field := range fields {
+		width = max(width, field.index+1)
+	}
+
+	var candidates []models.SheetCandidate
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows from sheet %s: %w", sheet, err)
+		}
+
+		candidate := models.SheetCandidate{Name: sheet, MaxScore: len(fields)}
+		if len(rows) > 0 {
+			for _, field := range fields {
+				if strings.EqualFold(strings.TrimSpace(utils.SafeGetCellValue(rows[0], field.index)), field.header) {
+					candidate.Score++
+				}
+			}
+		}
+		for _, row := range rows[:min(len(rows), previewRows)] {
+			preview := make([]string, width)
+			copy(preview, row)
+			candidate.Preview = append(candidate.Preview, preview)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// DetectSourceSheet recognises the sheet of a workbook holding the
+// attendance export by its header row, for exports whose sheet is not named
+// as configured. Without a single sheet matching confidently it returns an
+// AmbiguousSheetError.
+func (es *ExcelService) DetectSourceSheet(fileData []byte) (string, error) {
+	candidates, err := es.ScoreSheets(fileData)
+	if err != nil {
+		return "", err
+	}
+
+	if len(candidates) > 0 && candidates[0].Score >= confidentSheetScore &&
+		(len(candidates) == 1 || candidates[1].Score < candidates[0].Score) {
+		return candidates[0].Name, nil
+	}
+	return "", AmbiguousSheetError{Candidates: candidates}
+}
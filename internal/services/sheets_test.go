@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func createSheetsTestFile(t *testing.T, sheets map[string][]string) []byte {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for sheet, header := range sheets {
+		f.NewSheet(sheet)
+		f.SetSheetRow(sheet, "A1", &header)
+		f.SetSheetRow(sheet, "A2", &[]string{"1", "Test User"})
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestMatchSheets(t *testing.T) {
+	sheets := []string{"přehled", "tým A", "tým B", "docházka"}
+
+	tests := []struct {
+		name        string
+		selections  []string
+		wantMatched []string
+		wantMissing []string
+	}{
+		{"single name", []string{"docházka"}, []string{"docházka"}, nil},
+		{"several names in workbook order", []string{"tým B", "tým A"}, []string{"tým A", "tým B"}, nil},
+		{"pattern", []string{"tým *"}, []string{"tým A", "tým B"}, nil},
+		{"pattern and name overlapping", []string{"tým ?", "tým A"}, []string{"tým A", "tým B"}, nil},
+		{"missing name", []string{"tým C", "tým A"}, []string{"tým A"}, []string{"tým C"}},
+		{"pattern without match", []string{"team*"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, missing := MatchSheets(sheets, tt.selections)
+			if !reflect.DeepEqual(matched, tt.wantMatched) || !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("Expected %v and missing %v, got %v and missing %v", tt.wantMatched, tt.wantMissing, matched, missing)
+			}
+		})
+	}
+}
+
+func TestDetectSourceSheet(t *testing.T) {
+	exportHeader := []string{"ID", "Člen", "", "", "", "", "Účast potvrzena", "", "Typ události", "Název události", "", "Od", "Do"}
+	// Another sheet with some of the columns in place
+	summaryHeader := []string{"ID", "Člen", "", "", "", "", "Hodiny", "", "Typ události"}
+	excelService := NewExcelService("", "docházka")
+
+	sheet, err := excelService.DetectSourceSheet(createSheetsTestFile(t, map[string][]string{
+		"přehled":     summaryHeader,
+		"export 2024": exportHeader,
+	}))
+	if err != nil || sheet != "export 2024" {
+		t.Errorf("Expected the export sheet to be recognised, got %q (%v)", sheet, err)
+	}
+
+	// Two sheets match equally well
+	_, err = excelService.DetectSourceSheet(createSheetsTestFile(t, map[string][]string{
+		"tým A":   exportHeader,
+		"tým B":   exportHeader,
+		"přehled": summaryHeader,
+	}))
+	var ambiguousErr AmbiguousSheetError
+	if !errors.As(err, &ambiguousErr) {
+		t.Fatalf("Expected an AmbiguousSheetError, got %v", err)
+	}
+	candidates := ambiguousErr.Candidates
+	if len(candidates) != 4 || candidates[0].Score != 6 || candidates[1].Score != 6 || candidates[2].Name != "přehled" || candidates[2].Score != 2 {
+		t.Errorf("Expected the candidates ranked by score, got %+v", candidates)
+	}
+	if len(candidates[0].Preview) != 2 || len(candidates[0].Preview[1]) != len(exportHeader) || candidates[0].Preview[1][1] != "Test User" {
+		t.Errorf("Expected a preview of the header and data row, got %v", candidates[0].Preview)
+	}
+
+	// Nothing looks like the export
+	_, err = excelService.DetectSourceSheet(createSheetsTestFile(t, map[string][]string{"přehled": summaryHeader}))
+	if !errors.As(err, &ambiguousErr) {
+		t.Errorf("Expected an AmbiguousSheetError, got %v", err)
+	}
+}
+
+func TestIsSheetNotFoundErrorWrapped(t *testing.T) {
+	err := fmt.Errorf("export.xlsx: %w", SheetNotFoundError{SheetName: "docházka"})
+	if snfErr, ok := IsSheetNotFoundError(err); !ok || snfErr.SheetName != "docházka" {
+		t.Errorf("Expected the wrapped error to be recognised, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+const submissionsFileName = "submissions.json"
+
+// SubmissionStore keeps the reports members handed in for approval. When a
+// data directory is configured, submissions are also written to disk.
+type SubmissionStore struct {
+	submissions map[string]models.Submission
+	mutex       sync.RWMutex
+	snapshot    *jsonSnapshotStore[string, models.Submission]
+}
+
+func NewSubmissionStore(dataDir string) *SubmissionStore {
+	ss := &SubmissionStore{
+		submissions: make(map[string]models.Submission),
+		snapshot:    newJSONSnapshotStore[string, models.Submission](dataDir, submissionsFileName, "submissions"),
+	}
+
+	if err := ss.load(); err != nil {
+		log.Printf("Error loading submissions: %v", err)
+	}
+
+	return ss
+}
+
+// Submit hands in a report version for approval. Earlier pending submissions
+// of the same member and period are superseded by it.
+func (ss *SubmissionStore) Submit(version models.ReportVersion, memberEmail string) models.Submission {
+	submission := models.Submission{
+		ID:          utils.GenerateToken(),
+		VersionID:   version.ID,
+		Name:        version.Name,
+		Year:        version.Year,
+		Month:       version.Month,
+		MemberEmail: memberEmail,
+		Status:      models.SubmissionPending,
+		SubmittedAt: time.Now(),
+	}
+
+	ss.mutex.Lock()
+	for id, s := range ss.submissions {
+		if s.Name == submission.Name && s.Year == submission.Year && s.Month == submission.Month && s.Status == models.SubmissionPending {
+			s.Status = models.SubmissionSuperseded
+			ss.submissions[id] = s
+		}
+	}
+	ss.submissions[submission.ID] = submission
+	ss.mutex.Unlock()
+
+	ss.persist()
+
+	return submission
+}
+
+func (ss *SubmissionStore) Get(id string) (models.Submission, bool) {
+	ss.mutex.RLock()
+	submission, ok := ss.submissions[id]
+	ss.mutex.RUnlock()
+
+	return submission, ok
+}
+
+// List returns submissions with the given status (all when empty) for the
+// given period (all when year or month is zero), newest first
+func (ss *SubmissionStore) List(status string, year, month int) []models.Submission {
+	ss.mutex.RLock()
+	var submissions []models.Submission
+	for _, s := range ss.submissions {
+		if status != "" && s.Status != status {
+			continue
+		}
+		if (year != 0 && s.Year != year) || (month != 0 && s.Month != month) {
+			continue
+		}
+		submissions = append(submissions, s)
+	}
+	ss.mutex.RUnlock()
+
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissions[i].SubmittedAt.After(submissions[j].SubmittedAt)
+	})
+	return submissions
+}
+
+// Review approves or rejects a pending submission
+func (ss *SubmissionStore) Review(id string, approve bool, reviewer, comment string) (models.Submission, error) {
+	ss.mutex.Lock()
+	submission, ok := ss.submissions[id]
+	if !ok {
+		ss.mutex.Unlock()
+		return models.Submission{}, fmt.Errorf("submission %q not found", id)
+	}
+	if submission.Status != models.SubmissionPending {
+		ss.mutex.Unlock()
+		return models.Submission{}, fmt.Errorf("submission %q is already %s", id, submission.Status)
+	}
+
+	submission.Status = models.SubmissionRejected
+	if approve {
+		submission.Status = models.SubmissionApproved
+	}
+	submission.Reviewer = reviewer
+	submission.Comment = comment
+	submission.ReviewedAt = time.Now()
+	ss.submissions[id] = submission
+	ss.mutex.Unlock()
+
+	ss.persist()
+
+	return submission, nil
+}
+
+// load reads previously persisted submissions from the data directory
+func (ss *SubmissionStore) load() error {
+	submissions, err := ss.snapshot.load()
+	if err != nil {
+		return err
+	}
+
+	ss.mutex.Lock()
+	for _, s := range submissions {
+		ss.submissions[s.ID] = s
+	}
+	ss.mutex.Unlock()
+
+	return nil
+}
+
+// persist writes all submissions to the data directory
+func (ss *SubmissionStore) persist() {
+	ss.snapshot.save(&ss.mutex, ss.submissions)
+}
+
+// Latest returns the most recent submission of a member for a period that
+// was not superseded
+func (ss *SubmissionStore) Latest(name string, year, month int) (models.Submission, bool) {
+	var latest models.Submission
+	found := false
+
+	ss.mutex.RLock()
+	for _, s := range ss.submissions {
+		if s.Name != name || s.Year != year || s.Month != month || s.Status == models.SubmissionSuperseded {
+			continue
+		}
+		if !found || s.SubmittedAt.After(latest.SubmittedAt) {
+			latest = s
+			found = true
+		}
+	}
+	ss.mutex.RUnlock()
+
+	return latest, found
+}
+
+// MemberEmail returns the address a member gave with their most recent
+// submission that had one
+func (ss *SubmissionStore) MemberEmail(name string) (string, bool) {
+	var latest models.Submission
+	found := false
+
+	ss.mutex.RLock()
+	for _, s := range ss.submissions {
+		if s.Name != name || s.MemberEmail == "" {
+			continue
+		}
+		if !found || s.SubmittedAt.After(latest.SubmittedAt) {
+			latest = s
+			found = true
+		}
+	}
+	ss.mutex.RUnlock()
+
+	return latest.MemberEmail, found
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+
+	"timesheet-filler/internal/models"
+)
+
+func TestSubmissionStore(t *testing.T) {
+	dataDir := t.TempDir()
+	versionStore := NewVersionStore("")
+	submissionStore := NewSubmissionStore(dataDir)
+
+	first := versionStore.Add("Novák Jan", 2024, 3, nil, "first.xlsx")
+	second := versionStore.Add("Novák Jan", 2024, 3, nil, "second.xlsx")
+
+	older := submissionStore.Submit(first, "")
+	newer := submissionStore.Submit(second, "jan@example.com")
+
+	// A new submission supersedes the pending one of the same period
+	if s, _ := submissionStore.Get(older.ID); s.Status != models.SubmissionSuperseded {
+		t.Errorf("Expected older submission to be superseded, got %q", s.Status)
+	}
+	if pending := submissionStore.List(models.SubmissionPending, 2024, 3); len(pending) != 1 || pending[0].ID != newer.ID {
+		t.Fatalf("Expected only the newer submission to be pending, got %v", pending)
+	}
+
+	reviewed, err := submissionStore.Review(newer.ID, false, "Coordinator", "Missing practice on 5th")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if reviewed.Status != models.SubmissionRejected || reviewed.Comment != "Missing practice on 5th" {
+		t.Errorf("Unexpected reviewed submission %+v", reviewed)
+	}
+
+	if _, err := submissionStore.Review(newer.ID, true, "Coordinator", ""); err == nil {
+		t.Error("Expected reviewing a rejected submission to fail")
+	}
+
+	// Submissions are reloaded from the data directory
+	reloaded := NewSubmissionStore(dataDir)
+	if s, ok := reloaded.Get(newer.ID); !ok || s.Status != models.SubmissionRejected {
+		t.Errorf("Expected persisted rejected submission, got %+v", s)
+	}
+	if all := reloaded.List("", 0, 0); len(all) != 2 {
+		t.Errorf("Expected 2 submissions, got %d", len(all))
+	}
+}
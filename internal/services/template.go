@@ -1,6 +1,7 @@
 package services
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
 	"path/filepath"
@@ -14,13 +15,36 @@ import (
 type TemplateService struct {
 	templateDir string
 	translator  *i18n.Translator
+	basePath    string
+	branding    models.Branding
 }
 
-func NewTemplateService(templateDir string, translator *i18n.Translator) *TemplateService {
-	return &TemplateService{
+type TemplateOption func(*TemplateService)
+
+// WithBasePath prefixes the links of the rendered pages, for tenants served
+// under a path prefix
+func WithBasePath(basePath string) TemplateOption {
+	return func(ts *TemplateService) {
+		ts.basePath = strings.TrimSuffix(basePath, "/")
+	}
+}
+
+// WithBranding sets the logo and accent color of the rendered pages
+func WithBranding(branding models.Branding) TemplateOption {
+	return func(ts *TemplateService) {
+		ts.branding = branding
+	}
+}
+
+func NewTemplateService(templateDir string, translator *i18n.Translator, opts ...TemplateOption) *TemplateService {
+	ts := &TemplateService{
 		templateDir: templateDir,
 		translator:  translator,
 	}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	return ts
 }
 
 func (ts *TemplateService) RenderTemplate(w http.ResponseWriter, tmplName string, data interface{}, statusCode int, lang string) error {
@@ -32,6 +56,21 @@ func (ts *TemplateService) RenderTemplate(w http.ResponseWriter, tmplName string
 		"t": func(key string) string {
 			return ts.translator.Translate(key, lang)
 		},
+		"tf": func(key string, args ...interface{}) string {
+			return fmt.Sprintf(ts.translator.Translate(key, lang), args...)
+		},
+		"add": func(a, b int) int {
+			return a + b
+		},
+		"list": func(items ...string) []string {
+			return items
+		},
+		"path": func(path string) string {
+			return ts.basePath + path
+		},
+		"issue": func(issue models.ValidationIssue) string {
+			return fmt.Sprintf(ts.translator.Translate("validation_"+issue.Code, lang), issue.Args...)
+		},
 	}
 
 	tmpl, err := template.New("").Funcs(funcMap).ParseFiles(
@@ -50,6 +89,7 @@ func (ts *TemplateService) RenderTemplate(w http.ResponseWriter, tmplName string
 		CurrentYear: time.Now().Year(),
 		CurrentPage: currentPage,
 		Language:    lang,
+		Branding:    ts.branding,
 	}
 
 	w.WriteHeader(statusCode)
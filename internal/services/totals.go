@@ -0,0 +1,67 @@
+package services
+
+import (
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+// RowDuration returns the time worked in a single table row. Rows with
+// invalid or reversed times count as zero.
+func RowDuration(row models.TableRow) time.Duration {
+	start, end, err := utils.ParseTimeRange(row.StartTime, row.EndTime)
+	if err != nil || !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// ComputeTotals calculates per-row durations and the total for a report
+func ComputeTotals(tableData []models.TableRow) models.ReportTotals {
+	totals := models.ReportTotals{
+		RowHours: make([]float64, len(tableData)),
+	}
+
+	var total time.Duration
+	for i, row := range tableData {
+		duration := RowDuration(row)
+		totals.RowHours[i] = duration.Hours()
+		total += duration
+		if duration > 0 {
+			totals.RowCount++
+		}
+		if row.EventType != "" {
+			if totals.TypeHours == nil {
+				totals.TypeHours = make(map[string]float64)
+			}
+			totals.TypeHours[row.EventType] += duration.Hours()
+		}
+	}
+	totals.TotalHours = total.Hours()
+
+	return totals
+}
+
+// ReportYear returns the year of the first valid date in the report, falling
+// back to the current year when no row has a date.
+func ReportYear(tableData []models.TableRow) int {
+	for _, row := range tableData {
+		if date, err := time.Parse("2006-01-02", row.Date); err == nil {
+			return date.Year()
+		}
+	}
+	return time.Now().Year()
+}
+
+// ReportPeriod returns the first day of the month a report covers, taken
+// from its first dated entry, or the current month without entries
+func ReportPeriod(tableData []models.TableRow) time.Time {
+	for _, row := range tableData {
+		if date, err := time.Parse("2006-01-02", row.Date); err == nil {
+			return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
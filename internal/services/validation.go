@@ -0,0 +1,242 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"timesheet-filler/internal/holidays"
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+// Validation issue codes. Each code has a matching "validation_<code>"
+// translation key used to render the message on the edit page.
+const (
+	IssueInvalidDate    = "invalid_date"
+	IssueInvalidTime    = "invalid_time"
+	IssueEndBeforeStart = "end_before_start"
+	IssueOutsideMonth   = "outside_month"
+	IssueOverlap        = "overlap"
+	IssueDuplicate      = "duplicate"
+	IssueDailyHours     = "daily_hours"
+	IssueTooManyRows    = "too_many_rows"
+	IssueHoliday        = "holiday"
+	IssueTentative      = "tentative"
+)
+
+// Issue severities. Errors block report generation unless overridden,
+// warnings are only displayed.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// tableWideIssueRowIdx marks issues that concern the whole table
+const tableWideIssueRowIdx = -1
+
+type ValidationService struct {
+	maxDailyHours  float64
+	mappingFor     ReportMappingFunc
+	splitOvernight bool
+	splitOptions   EventSplitOptions
+	holidays       *holidays.Calendar
+}
+
+func NewValidationService(
+	maxDailyHours float64,
+	mappingFor ReportMappingFunc,
+	splitOvernight bool,
+	splitOptions EventSplitOptions,
+	calendar *holidays.Calendar,
+) *ValidationService {
+	return &ValidationService{
+		maxDailyHours:  maxDailyHours,
+		mappingFor:     mappingFor,
+		splitOvernight: splitOvernight,
+		splitOptions:   splitOptions,
+		holidays:       calendar,
+	}
+}
+
+// Normalize prepares edited rows for validation. When enabled, entries whose
+// end time is before their start time are treated as crossing midnight and
+// split into per-day rows, using the same daily caps as extraction.
+func (vs *ValidationService) Normalize(rows []models.TableRow) []models.TableRow {
+	if !vs.splitOvernight {
+		return rows
+	}
+	return SplitOvernightRows(rows, vs.splitOptions)
+}
+
+// parsedRow holds the parsed form of a table row for the checks that need to
+// compare rows against each other.
+type parsedRow struct {
+	index int
+	date  time.Time
+	start time.Time
+	end   time.Time
+}
+
+// Validate checks the rows submitted from the edit page for the selected month
+// and returns every problem found. Rows are referenced by their index in the
+// submitted slice; table-wide issues use a row index of -1.
+func (vs *ValidationService) Validate(rows []models.TableRow, month int) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+	var parsed []parsedRow
+	inMonth := rowsInMonth(rows, month)
+
+	for i, row := range rows {
+		date, err := time.Parse("2006-01-02", row.Date)
+		if err != nil {
+			issues = append(issues, newIssue(i, IssueInvalidDate, SeverityError, row.Date))
+			continue
+		}
+
+		start, end, err := utils.ParseTimeRange(row.StartTime, row.EndTime)
+		if err != nil {
+			issues = append(issues, newIssue(i, IssueInvalidTime, SeverityError))
+			continue
+		}
+
+		if !inMonth[i] {
+			issues = append(issues, newIssue(i, IssueOutsideMonth, SeverityError, row.Date))
+		}
+
+		if row.Tentative {
+			issues = append(issues, newIssue(i, IssueTentative, SeverityWarning))
+		}
+
+		// Work on public holidays is paid differently, so point it out
+		if name, ok := vs.holidays.Holiday(date); ok {
+			issues = append(issues, newIssue(i, IssueHoliday, SeverityWarning, name))
+		}
+
+		if !end.After(start) {
+			issues = append(issues, newIssue(i, IssueEndBeforeStart, SeverityError, row.StartTime, row.EndTime))
+			continue
+		}
+
+		parsed = append(parsed, parsedRow{index: i, date: date, start: start, end: end})
+	}
+
+	issues = append(issues, vs.checkOverlaps(parsed)...)
+	issues = append(issues, vs.checkDailyHours(parsed)...)
+
+	// The template of the report period sets how many rows fit on a sheet
+	if maxRows := vs.mappingFor(rows).MaxRows; maxRows > 0 && len(rows) > maxRows {
+		// Extra rows continue on additional report sheets, so this only warns
+		issues = append(issues, newIssue(tableWideIssueRowIdx, IssueTooManyRows, SeverityWarning, len(rows), maxRows))
+	}
+
+	sort.SliceStable(issues, func(a, b int) bool {
+		return issues[a].Row < issues[b].Row
+	})
+
+	return issues
+}
+
+// rowsInMonth reports which rows belong to the month. Besides the rows dated
+// in it, these are the rows continuing an overnight entry that started in the
+// month, as split by Normalize, so that an entry starting on its last evening
+// is reported whole.
+func rowsInMonth(rows []models.TableRow, month int) []bool {
+	inMonth := make([]bool, len(rows))
+	for i, row := range rows {
+		date, err := time.Parse("2006-01-02", row.Date)
+		if err != nil {
+			continue
+		}
+		if int(date.Month()) == month {
+			inMonth[i] = true
+			continue
+		}
+
+		// A continuation starts at midnight on the day after the previous
+		// row of the same entry, which ended at midnight
+		if i == 0 || !inMonth[i-1] || row.StartTime != "00:00" {
+			continue
+		}
+		prev := rows[i-1]
+		prevDate, err := time.Parse("2006-01-02", prev.Date)
+		inMonth[i] = err == nil && prev.EndTime == "00:00" && prev.Note == row.Note &&
+			prevDate.AddDate(0, 0, 1).Equal(date)
+	}
+	return inMonth
+}
+
+func (vs *ValidationService) checkOverlaps(rows []parsedRow) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	for i := 0; i < len(rows); i++ {
+		for j := 0; j < i; j++ {
+			a, b := rows[j], rows[i]
+			if !a.date.Equal(b.date) {
+				continue
+			}
+
+			if a.start.Equal(b.start) && a.end.Equal(b.end) {
+				issues = append(issues, newIssue(b.index, IssueDuplicate, SeverityError, a.index+1))
+				break
+			}
+
+			if b.start.Before(a.end) && a.start.Before(b.end) {
+				issues = append(issues, newIssue(b.index, IssueOverlap, SeverityError, a.index+1))
+				break
+			}
+		}
+	}
+
+	return issues
+}
+
+func (vs *ValidationService) checkDailyHours(rows []parsedRow) []models.ValidationIssue {
+	if vs.maxDailyHours <= 0 {
+		return nil
+	}
+
+	totals := make(map[time.Time]time.Duration)
+	for _, row := range rows {
+		totals[row.date] += row.end.Sub(row.start)
+	}
+
+	var issues []models.ValidationIssue
+	for _, row := range rows {
+		hours := totals[row.date].Hours()
+		if hours > vs.maxDailyHours {
+			issues = append(issues, newIssue(row.index, IssueDailyHours, SeverityError,
+				fmt.Sprintf("%.1f", hours), fmt.Sprintf("%.1f", vs.maxDailyHours)))
+		}
+	}
+
+	return issues
+}
+
+// HasBlockingIssues reports whether any issue prevents report generation
+func HasBlockingIssues(issues []models.ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupIssuesByRow indexes issues by row so the edit page can render them
+// inline next to the offending row.
+func GroupIssuesByRow(issues []models.ValidationIssue) map[int][]models.ValidationIssue {
+	grouped := make(map[int][]models.ValidationIssue)
+	for _, issue := range issues {
+		grouped[issue.Row] = append(grouped[issue.Row], issue)
+	}
+	return grouped
+}
+
+func newIssue(row int, code, severity string, args ...interface{}) models.ValidationIssue {
+	return models.ValidationIssue{
+		Row:      row,
+		Code:     code,
+		Severity: severity,
+		Args:     args,
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"testing"
+
+	"timesheet-filler/internal/holidays"
+	"timesheet-filler/internal/models"
+)
+
+func TestValidate(t *testing.T) {
+	calendar, err := holidays.New("CZ", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	validationService := NewValidationService(10, FixedReportMapping(models.ReportMapping{MaxRows: 3}), false, EventSplitOptions{}, calendar)
+
+	tests := []struct {
+		name      string
+		rows      []models.TableRow
+		wantCodes []string
+	}{
+		{
+			name: "valid rows",
+			rows: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "18:00", EndTime: "20:00"},
+				{Date: "2024-03-10", StartTime: "10:00", EndTime: "12:00"},
+			},
+			wantCodes: nil,
+		},
+		{
+			name: "end before start",
+			rows: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "18:00", EndTime: "17:00"},
+			},
+			wantCodes: []string{IssueEndBeforeStart},
+		},
+		{
+			name: "invalid date and time",
+			rows: []models.TableRow{
+				{Date: "", StartTime: "18:00", EndTime: "19:00"},
+				{Date: "2024-03-09", StartTime: "", EndTime: "19:00"},
+			},
+			wantCodes: []string{IssueInvalidDate, IssueInvalidTime},
+		},
+		{
+			name: "midnight end time",
+			rows: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "18:00", EndTime: "00:00"},
+				{Date: "2024-03-10", StartTime: "00:00", EndTime: "02:00"},
+			},
+			wantCodes: nil,
+		},
+		{
+			name: "outside month",
+			rows: []models.TableRow{
+				{Date: "2024-04-02", StartTime: "18:00", EndTime: "19:00"},
+			},
+			wantCodes: []string{IssueOutsideMonth},
+		},
+		{
+			name: "overnight entry on the last day of the month",
+			rows: []models.TableRow{
+				{Date: "2025-03-31", StartTime: "22:00", EndTime: "00:00", Note: "Night game"},
+				{Date: "2025-04-01", StartTime: "00:00", EndTime: "02:00", Note: "Night game"},
+			},
+			wantCodes: nil,
+		},
+		{
+			name: "next month row after an entry ending at midnight",
+			rows: []models.TableRow{
+				{Date: "2025-03-31", StartTime: "22:00", EndTime: "00:00", Note: "Night game"},
+				{Date: "2025-04-01", StartTime: "00:00", EndTime: "02:00", Note: "Practice"},
+			},
+			wantCodes: []string{IssueOutsideMonth},
+		},
+		{
+			name: "tentative attendance",
+			rows: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "18:00", EndTime: "19:00", Tentative: true},
+			},
+			wantCodes: []string{IssueTentative},
+		},
+		{
+			name: "public holiday",
+			rows: []models.TableRow{
+				{Date: "2024-03-29", StartTime: "18:00", EndTime: "19:00"},
+			},
+			wantCodes: []string{IssueHoliday},
+		},
+		{
+			name: "overlap and duplicate",
+			rows: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "10:00", EndTime: "12:00"},
+				{Date: "2024-03-09", StartTime: "10:00", EndTime: "12:00"},
+				{Date: "2024-03-09", StartTime: "11:00", EndTime: "13:00"},
+			},
+			wantCodes: []string{IssueDuplicate, IssueOverlap},
+		},
+		{
+			name: "daily hours exceeded",
+			rows: []models.TableRow{
+				{Date: "2024-03-09", StartTime: "06:00", EndTime: "12:00"},
+				{Date: "2024-03-09", StartTime: "13:00", EndTime: "19:00"},
+			},
+			wantCodes: []string{IssueDailyHours, IssueDailyHours},
+		},
+		{
+			name: "too many rows",
+			rows: []models.TableRow{
+				{Date: "2024-03-01", StartTime: "10:00", EndTime: "11:00"},
+				{Date: "2024-03-02", StartTime: "10:00", EndTime: "11:00"},
+				{Date: "2024-03-03", StartTime: "10:00", EndTime: "11:00"},
+				{Date: "2024-03-04", StartTime: "10:00", EndTime: "11:00"},
+			},
+			wantCodes: []string{IssueTooManyRows},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validationService.Validate(tt.rows, 3)
+			if len(issues) != len(tt.wantCodes) {
+				t.Fatalf("Validate() returned %d issues (%v), want %d", len(issues), issues, len(tt.wantCodes))
+			}
+			for i, code := range tt.wantCodes {
+				if issues[i].Code != code {
+					t.Errorf("issue %d: got code %q, want %q", i, issues[i].Code, code)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSplitAcrossMonthEnd(t *testing.T) {
+	calendar, err := holidays.New("CZ", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	validationService := NewValidationService(10, FixedReportMapping(models.ReportMapping{}), true, EventSplitOptions{}, calendar)
+
+	// An entry over the last night of March is split into a row on April 1,
+	// which still belongs to the March report
+	rows := validationService.Normalize([]models.TableRow{
+		{Date: "2025-03-31", StartTime: "22:00", EndTime: "02:00", Note: "Night game"},
+	})
+	if len(rows) != 2 {
+		t.Fatalf("Normalize() returned %d rows (%v), want 2", len(rows), rows)
+	}
+	if issues := validationService.Validate(rows, 3); len(issues) != 0 {
+		t.Errorf("Validate() returned %v, want no issues", issues)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"timesheet-filler/internal/models"
+	"timesheet-filler/internal/utils"
+)
+
+const versionsFileName = "versions.json"
+
+// VersionStore keeps every generated report as a numbered version per member
+// and period. When a data directory is configured, versions are also written
+// to disk so the history survives restarts.
+type VersionStore struct {
+	versions map[string]models.ReportVersion
+	mutex    sync.RWMutex
+	snapshot *jsonSnapshotStore[string, models.ReportVersion]
+}
+
+func NewVersionStore(dataDir string) *VersionStore {
+	vs := &VersionStore{
+		versions: make(map[string]models.ReportVersion),
+		snapshot: newJSONSnapshotStore[string, models.ReportVersion](dataDir, versionsFileName, "report versions"),
+	}
+
+	if err := vs.load(); err != nil {
+		log.Printf("Error loading report versions: %v", err)
+	}
+
+	return vs
+}
+
+// Add records a generated report and returns it with its version number
+func (vs *VersionStore) Add(name string, year, month int, tableData []models.TableRow, fileName string) models.ReportVersion {
+	vs.mutex.Lock()
+	number := 1
+	for _, v := range vs.versions {
+		if v.Name == name && v.Year == year && v.Month == month && v.Number >= number {
+			number = v.Number + 1
+		}
+	}
+
+	version := models.ReportVersion{
+		ID:         utils.GenerateToken(),
+		Name:       name,
+		Year:       year,
+		Month:      month,
+		Number:     number,
+		TableData:  tableData,
+		TotalHours: ComputeTotals(tableData).TotalHours,
+		FileName:   fileName,
+		CreatedAt:  time.Now(),
+	}
+	vs.versions[version.ID] = version
+	vs.mutex.Unlock()
+
+	vs.persist()
+
+	return version
+}
+
+func (vs *VersionStore) Get(id string) (models.ReportVersion, bool) {
+	vs.mutex.RLock()
+	version, ok := vs.versions[id]
+	vs.mutex.RUnlock()
+
+	return version, ok
+}
+
+// List returns the versions of a member for a period, oldest first
+func (vs *VersionStore) List(name string, year, month int) []models.ReportVersion {
+	vs.mutex.RLock()
+	var versions []models.ReportVersion
+	for _, v := range vs.versions {
+		if v.Name == name && v.Year == year && v.Month == month {
+			versions = append(versions, v)
+		}
+	}
+	vs.mutex.RUnlock()
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Number < versions[j].Number
+	})
+	return versions
+}
+
+// MarkEmailed records that a version was sent by email
+func (vs *VersionStore) MarkEmailed(id string) bool {
+	vs.mutex.Lock()
+	version, ok := vs.versions[id]
+	if ok {
+		version.Emailed = true
+		version.EmailedAt = time.Now()
+		vs.versions[id] = version
+	}
+	vs.mutex.Unlock()
+
+	if ok {
+		vs.persist()
+	}
+	return ok
+}
+
+// load reads previously persisted versions from the data directory
+func (vs *VersionStore) load() error {
+	versions, err := vs.snapshot.load()
+	if err != nil {
+		return err
+	}
+
+	vs.mutex.Lock()
+	for _, v := range versions {
+		vs.versions[v.ID] = v
+	}
+	vs.mutex.Unlock()
+
+	return nil
+}
+
+// persist writes all versions to the data directory
+func (vs *VersionStore) persist() {
+	vs.snapshot.save(&vs.mutex, vs.versions)
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// DefaultDateLayouts are the date and time formats understood by ParseDate,
+// tried in order
+var DefaultDateLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"2.1.2006 15:04",
+	"2.1.2006 15:04:05",
+	"2. 1. 2006 15:04",
+	"2. 1. 2006 15:04:05",
+	"1/2/06 15:04", // how excelize renders the default m/d/yy h:mm format
+	"2006-01-02",
+	"2.1.2006",
+	"2. 1. 2006",
+}
+
+var defaultDateParser = NewDateParser()
+
+// Numbers are only read as Excel serials when they fall in these years, so
+// that cells holding a bare year or day number are not taken for dates
+const (
+	minSerialYear = 1990
+	maxSerialYear = 2100
+)
+
+// DateParser parses dates and times from spreadsheet cells, which hold either
+// text in one of its layouts or an Excel serial number
+type DateParser struct {
+	layouts []string
+}
+
+// NewDateParser returns a parser trying the given layouts before the defaults
+func NewDateParser(layouts ...string) *DateParser {
+	var all []string
+	for _, layout := range layouts {
+		if layout = strings.TrimSpace(layout); layout != "" {
+			all = append(all, layout)
+		}
+	}
+	return &DateParser{layouts: append(all, DefaultDateLayouts...)}
+}
+
+// Parse reads a date and time. Serial numbers are interpreted in the 1904
+// date system of old Mac workbooks when date1904 is set, otherwise in the
+// 1900 system, and only accepted for dates from 1990 through 2100.
+func (p *DateParser) Parse(value string, date1904 bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		if serial <= 0 {
+			return time.Time{}, fmt.Errorf("invalid date serial %q", value)
+		}
+		date, err := excelize.ExcelDateToTime(serial, date1904)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date serial %q: %w", value, err)
+		}
+		if date.Year() < minSerialYear || date.Year() > maxSerialYear {
+			return time.Time{}, fmt.Errorf("date serial %q is out of range", value)
+		}
+		// Serials are fractions of a day, drop the floating point noise
+		return date.Round(time.Second), nil
+	}
+
+	for _, layout := range p.layouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", value)
+}
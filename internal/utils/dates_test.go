@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateParserParse(t *testing.T) {
+	parser := NewDateParser("02/01/2006 15:04")
+
+	tests := []struct {
+		name     string
+		input    string
+		date1904 bool
+		want     string
+	}{
+		{
+			name:  "iso",
+			input: "2024-03-15 18:00",
+			want:  "2024-03-15 18:00:00",
+		},
+		{
+			name:  "iso with seconds",
+			input: "2024-03-15 18:00:30",
+			want:  "2024-03-15 18:00:30",
+		},
+		{
+			name:  "iso with T",
+			input: "2024-03-15T18:00:00",
+			want:  "2024-03-15 18:00:00",
+		},
+		{
+			name:  "czech",
+			input: "15.3.2024 18:00",
+			want:  "2024-03-15 18:00:00",
+		},
+		{
+			name:  "czech with spaces",
+			input: "15. 3. 2024 8:05",
+			want:  "2024-03-15 08:05:00",
+		},
+		{
+			name:  "excel serial",
+			input: "45366.75",
+			want:  "2024-03-15 18:00:00",
+		},
+		{
+			name:     "excel serial in 1904 system",
+			input:    "43904.75",
+			date1904: true,
+			want:     "2024-03-15 18:00:00",
+		},
+		{
+			name:  "configured layout",
+			input: "15/03/2024 18:00",
+			want:  "2024-03-15 18:00:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.Parse(tt.input, tt.date1904)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got.Format(time.DateTime) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got.Format(time.DateTime))
+			}
+		})
+	}
+}
+
+func TestDateParserParseErrors(t *testing.T) {
+	parser := NewDateParser()
+
+	for _, input := range []string{"", "tomorrow", "-1", "15/03/2024 18:00", "2024", "15", "2958465"} {
+		if _, err := parser.Parse(input, false); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
@@ -68,14 +68,36 @@ func SafeGetCellValue(row []string, index int) string {
 	return ""
 }
 
+// ParseDateTime parses a date and time in any of the DefaultDateLayouts or
+// as an Excel serial number
 func ParseDateTime(input string) (time.Time, error) {
-	layout := "2006-01-02 15:04"
-	return time.Parse(layout, input)
+	return defaultDateParser.Parse(input, false)
 }
 
+// ParseDate is an alias of ParseDateTime
 func ParseDate(input string) (time.Time, error) {
-	layout := "2006-01-02 15:04"
-	return time.Parse(layout, input)
+	return defaultDateParser.Parse(input, false)
+}
+
+// ParseTimeRange parses start and end clock times in the HH:MM format. An end
+// time of 00:00 following a later start time means midnight at the end of the
+// day and is returned as 24:00.
+func ParseTimeRange(startStr, endStr string) (time.Time, time.Time, error) {
+	start, err := time.Parse("15:04", startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start time %q: %w", startStr, err)
+	}
+
+	end, err := time.Parse("15:04", endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end time %q: %w", endStr, err)
+	}
+
+	if end.Hour() == 0 && end.Minute() == 0 && start.After(end) {
+		end = end.Add(24 * time.Hour)
+	}
+
+	return start, end, nil
 }
 
 func RemoveDiacritics(s string) string {